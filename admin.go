@@ -0,0 +1,104 @@
+package webber
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
+)
+
+// initAdminServer creates the internal listener when ADMIN_PORT is set.
+// It serves pprof, expvar metrics, the worker statuses and the routes added
+// through Admin, optionally protected by basic auth (ADMIN_USERNAME/ADMIN_PASSWORD)
+// and an IP allow-list (ADMIN_ALLOWED_IPS, comma separated IPs or CIDRs).
+// An invalid allow-list entry fails the start of the App.
+func (a *App) initAdminServer(mode string) {
+	port, _ := a.Config.GetInt("ADMIN_PORT", 0)
+	if port == 0 {
+		return
+	}
+
+	host := a.Config.GetString("ADMIN_HOST", "localhost")
+
+	a.adminServer = newHTTPServer(a.container, "admin", host, port, mode)
+
+	r := a.adminServer.router
+
+	if allowed := a.Config.GetString("ADMIN_ALLOWED_IPS", ""); allowed != "" {
+		nets, err := parseIPNets(allowed)
+		if err != nil {
+			// The admin server does not start rather than trusting part of the list
+			a.adminServer.err = fmt.Errorf("invalid ADMIN_ALLOWED_IPS: %w", err)
+		}
+		r.Use(allowIPs(nets))
+	}
+
+	username := a.Config.GetString("ADMIN_USERNAME", "")
+	password := a.Config.GetString("ADMIN_PASSWORD", "")
+	if username != "" && password != "" {
+		r.Use(gin.BasicAuth(gin.Accounts{username: password}))
+	}
+
+	pprof.Register(r)
+
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+
+	r.GET("/debug/workers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, a.Workers())
+	})
+}
+
+// Admin returns a group on the admin listener for internal endpoints.
+// Routes added while ADMIN_PORT is not configured are not served.
+func (a *App) Admin() *RouterGroup {
+	if a.adminServer == nil {
+		a.Logger().Warnf("Admin listener is not configured, set ADMIN_PORT to serve admin routes")
+		return &RouterGroup{app: a, server: &httpServer{}, group: gin.New().Group("/")}
+	}
+
+	return &RouterGroup{app: a, server: a.adminServer, group: &a.adminServer.router.RouterGroup}
+}
+
+// allowIPs rejects the requests whose remote address is not in one of the given networks.
+// The remote address of the connection is used, forwarding headers are ignored.
+func allowIPs(nets []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.RemoteIP())
+		for _, n := range nets {
+			if ip != nil && n.Contains(ip) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+func parseIPNets(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nets, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
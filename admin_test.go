@@ -0,0 +1,21 @@
+package webber_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xbmlz/webber/webbertest"
+)
+
+func TestAdminInvalidAllowedIPs(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"ADMIN_PORT":        "1",
+		"ADMIN_ALLOWED_IPS": "10.0.0.1, 10.0.0.0/8, internal",
+	}))
+
+	err := app.RunContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "ADMIN_ALLOWED_IPS") {
+		t.Errorf("expected the invalid ADMIN_ALLOWED_IPS to fail the start, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package webber
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xbmlz/webber/config"
+)
+
+// Command is a subcommand of the CLI run by Execute.
+type Command struct {
+	// Name is the word that selects the command, e.g. "migrate".
+	Name string
+	// Usage is a one line description printed in the help.
+	Usage string
+	// Run runs the command with the remaining arguments.
+	Run func(ctx context.Context, args []string) error
+}
+
+// configKeys are the keys read by webber, printed by the config print command.
+var configKeys = []string{
+	"APP_ENV", "GIN_MODE", "HTTP_HOST", "HTTP_PORT", "HTTP_LISTEN", "HTTP_SOCKET_MODE", "HTTP_PPROF", "OPENAPI_PATH", "OPENAPI_DOCS_PATH", "OPENAPI_PUBLIC",
+	"GRPC_HOST", "GRPC_PORT",
+	"CERT_FILE", "KEY_FILE",
+	"TLS_CLIENT_CA", "TLS_CLIENT_AUTH", "TLS_MIN_VERSION", "TLS_CIPHER_SUITES",
+	"WEBSOCKET_PING_INTERVAL", "WEBSOCKET_BUFFER", "WEBSOCKET_MAX_MESSAGE_SIZE", "WEBSOCKET_ALLOWED_ORIGINS", "WEBSOCKET_REDIS_CHANNEL",
+	"SSE_HEARTBEAT", "SSE_RETRY", "SSE_BUFFER", "SSE_HISTORY", "SSE_REDIS_CHANNEL",
+	"HEALTH_LIVENESS_PATH", "HEALTH_READINESS_PATH", "HEALTHCHECK_CERT_FILE", "HEALTHCHECK_KEY_FILE",
+	"SHUTDOWN_TIMEOUT", "SHUTDOWN_DELAY", "UPGRADE_TIMEOUT", "MODULES_DISABLED", "CONFIG_WATCH", "CONFIG_WATCH_INTERVAL",
+	"ADMIN_HOST", "ADMIN_PORT", "ADMIN_USERNAME", "ADMIN_PASSWORD", "ADMIN_ALLOWED_IPS",
+	"LOG_LEVEL", "LOG_FILE", "LOG_ENCODER", "LOG_MAX_BACKUPS", "LOG_MAX_SIZE", "LOG_MAX_AGE", "LOG_COMPRESS",
+	"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USERNAME", "DB_PASSWORD", "DB_NAME", "DB_PARAMS",
+	"DB_LOG_LEVEL", "DB_MAX_OPEN_CONNECTION", "DB_MAX_IDLE_CONNECTION", "DB_AUTO_MIGRATE",
+	"REDIS_HOST", "REDIS_PORT", "REDIS_USERNAME", "REDIS_PASSWORD", "REDIS_DB",
+	"STORAGE_DRIVER", "STORAGE_PATH", "STORAGE_URL", "STORAGE_SECRET",
+	"STORAGE_S3_ENDPOINT", "STORAGE_S3_REGION", "STORAGE_S3_BUCKET", "STORAGE_S3_ACCESS_KEY", "STORAGE_S3_SECRET_KEY", "STORAGE_S3_PATH_STYLE",
+}
+
+// AddCommand adds a subcommand to the CLI, it replaces a built-in command with the same name.
+func (a *App) AddCommand(cmd Command) {
+	a.commands = append(a.commands, cmd)
+}
+
+// Execute runs the subcommand selected by os.Args, serve by default.
+// The built-in commands are serve, migrate, seed, routes, openapi, cron list,
+// cron run <name>, config print and healthcheck.
+func (a *App) Execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := a.execute(ctx, os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+
+	return err
+}
+
+func (a *App) execute(ctx context.Context, args []string, w io.Writer) error {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		a.printUsage(w)
+		return nil
+	}
+
+	for _, cmd := range a.allCommands(w) {
+		if cmd.Name == name {
+			return cmd.Run(ctx, args)
+		}
+	}
+
+	a.printUsage(w)
+
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// allCommands returns the built-in commands followed by the commands added
+// with AddCommand, the latter take precedence.
+func (a *App) allCommands(w io.Writer) []Command {
+	commands := []Command{
+		{Name: "serve", Usage: "Start the servers, cron jobs and workers (default)", Run: func(ctx context.Context, _ []string) error {
+			return a.serve(ctx)
+		}},
+		{Name: "migrate", Usage: "Migrate the database models", Run: func(context.Context, []string) error {
+			return a.withDB(a.migrate)
+		}},
+		{Name: "seed", Usage: "Seed the database", Run: func(context.Context, []string) error {
+			return a.withDB(a.seed)
+		}},
+		{Name: "routes", Usage: "Print the registered routes", Run: func(context.Context, []string) error {
+			return a.printRoutes(w)
+		}},
+		{Name: "openapi", Usage: "Print the OpenAPI document of the routes", Run: func(context.Context, []string) error {
+			return a.ExportOpenAPI(w)
+		}},
+		{Name: "cron", Usage: "List the cron jobs (cron list) or run one now (cron run <name>)", Run: func(_ context.Context, args []string) error {
+			return a.cronCommand(w, args)
+		}},
+		{Name: "config", Usage: "Print the resolved configuration (config print)", Run: func(_ context.Context, args []string) error {
+			if len(args) != 1 || args[0] != "print" {
+				return errors.New("usage: config print")
+			}
+			return a.printConfig(w)
+		}},
+		{Name: "healthcheck", Usage: "Exit with a non-zero status if the server is not healthy", Run: func(ctx context.Context, _ []string) error {
+			return a.healthcheck(ctx)
+		}},
+	}
+
+	for _, cmd := range a.commands {
+		commands = slices.DeleteFunc(commands, func(c Command) bool { return c.Name == cmd.Name })
+		commands = append(commands, cmd)
+	}
+
+	return commands
+}
+
+func (a *App) printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [arguments]\n\nCommands:\n", os.Args[0])
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range a.allCommands(w) {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, cmd.Usage)
+	}
+	tw.Flush()
+}
+
+// withDB runs fn and closes the database afterwards.
+func (a *App) withDB(fn func() error) error {
+	if a.container.DB == nil {
+		return errDBNotConfigured
+	}
+	return errors.Join(fn(), a.container.DB.Close())
+}
+
+func (a *App) printRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	for _, s := range []*httpServer{a.httpServer, a.adminServer} {
+		if s == nil {
+			continue
+		}
+		for _, r := range s.router.Routes() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.name, r.Method, r.Path)
+		}
+	}
+
+	return nil
+}
+
+func (a *App) cronCommand(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: cron list | cron run <name>")
+	}
+
+	switch args[0] {
+	case "list":
+		if a.cron == nil {
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		fmt.Fprintf(tw, "NAME\tSPEC\tNEXT\n")
+		for _, j := range a.cron.jobs {
+			next := a.cron.Entry(j.id).Schedule.Next(time.Now())
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", j.name, j.spec, next.Format(time.DateTime))
+		}
+
+		return nil
+	case "run":
+		if len(args) != 2 {
+			return errors.New("usage: cron run <name>")
+		}
+
+		var job *cronJob
+		if a.cron != nil {
+			job = a.cron.job(args[1])
+		}
+		if job == nil {
+			return fmt.Errorf("unknown cron job %q", args[1])
+		}
+
+		job.fn()
+
+		return nil
+	default:
+		return fmt.Errorf("unknown cron command %q", args[0])
+	}
+}
+
+func (a *App) printConfig(w io.Writer) error {
+	keys := slices.Clone(configKeys)
+	if l, ok := a.Config.(config.Lister); ok {
+		keys = append(keys, l.Keys()...)
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	for _, k := range keys {
+		v := a.Config.GetString(k, "")
+		if v != "" && isSecretKey(k) {
+			v = "******"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", k, v)
+	}
+
+	return nil
+}
+
+func isSecretKey(key string) bool {
+	for _, s := range []string{"PASSWORD", "SECRET", "TOKEN", "KEY"} {
+		if strings.Contains(key, s) && key != "KEY_FILE" {
+			return true
+		}
+	}
+	return false
+}
+
+// healthcheck checks that the App is alive through its liveness endpoint,
+// it is meant to be used as a Docker HEALTHCHECK. The admin credentials are
+// sent to the admin listener, and the certificate of HEALTHCHECK_CERT_FILE and
+// HEALTHCHECK_KEY_FILE is presented to a server verifying client certificates.
+func (a *App) healthcheck(ctx context.Context) error {
+	if a.health.livenessPath == "" {
+		return errors.New("the liveness endpoint is disabled, see HEALTH_LIVENESS_PATH")
+	}
+
+	s, scheme := a.httpServer, "http"
+	if a.adminServer != nil {
+		s = a.adminServer
+	} else if s.certFile != "" && s.keyFile != "" {
+		scheme = "https"
+	}
+
+	host := s.host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(s.port)), a.health.livenessPath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	if s == a.adminServer {
+		username := a.Config.GetString("ADMIN_USERNAME", "")
+		password := a.Config.GetString("ADMIN_PASSWORD", "")
+		if username != "" && password != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		// The certificate is issued for the public name, not for the local address
+		InsecureSkipVerify: true, //nolint:gosec
+	}
+
+	certFile := a.Config.GetString("HEALTHCHECK_CERT_FILE", "")
+	keyFile := a.Config.GetString("HEALTHCHECK_KEY_FILE", "")
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("loading the healthcheck certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+
+	// Connect through the unix socket the server listens on
+	if path, ok := strings.CutPrefix(s.listen, "unix://"); ok && s == a.httpServer {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second, Transport: transport}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,440 @@
+package webber
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec encodes responses and decodes requests of a media type.
+// A codec that cannot encode every value, such as ProtobufCodec, implements
+// CanEncode(v interface{}) bool so that another media type is negotiated.
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// JSONCodec encodes with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLCodec encodes with encoding/xml, which cannot encode maps.
+type XMLCodec struct{}
+
+// CanEncode reports whether v holds no map, such as a gin.H.
+func (XMLCodec) CanEncode(v interface{}) bool {
+	return v == nil || !hasMap(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// hasMap reports whether values of t can hold a map encoding/xml would fail on.
+// The fields of type interface{} are only known once encoded.
+func hasMap(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if t.Implements(xmlMarshaler) || reflect.PointerTo(t).Implements(xmlMarshaler) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return hasMap(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.IsExported() && f.Tag.Get("xml") != "-" && hasMap(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var xmlMarshaler = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// YAMLCodec encodes with gopkg.in/yaml.v3. The values go through encoding/json
+// so that the field names are read from the json tags, like with JSON.
+type YAMLCodec struct{}
+
+func (YAMLCodec) Encode(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON is YAML, the node keeps the order of the fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (YAMLCodec) Decode(r io.Reader, v interface{}) error {
+	var doc interface{}
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// blockStyle drops the flow style and the quotes of the node parsed from JSON,
+// the strings that would be read as another type stay quoted.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+var (
+	msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+	cborHandle    = &codec.CborHandle{}
+)
+
+// MsgpackCodec encodes MessagePack, the field names are read from the codec or json tags.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Encode(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, msgpackHandle).Encode(v)
+}
+
+func (MsgpackCodec) Decode(r io.Reader, v interface{}) error {
+	return codec.NewDecoder(r, msgpackHandle).Decode(v)
+}
+
+// CBORCodec encodes CBOR, the field names are read from the codec or json tags.
+type CBORCodec struct{}
+
+func (CBORCodec) Encode(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, cborHandle).Encode(v)
+}
+
+func (CBORCodec) Decode(r io.Reader, v interface{}) error {
+	return codec.NewDecoder(r, cborHandle).Decode(v)
+}
+
+// ProtobufCodec encodes proto messages.
+type ProtobufCodec struct{}
+
+var errNotProtoMessage = errors.New("value is not a proto message")
+
+func (ProtobufCodec) CanEncode(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+func (ProtobufCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errNotProtoMessage
+	}
+
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+func (ProtobufCodec) Decode(r io.Reader, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errNotProtoMessage
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(b, m)
+}
+
+type mediaCodec struct {
+	mediaType string
+	codec     Codec
+}
+
+// codecs are the codecs of an App by media type, the first one is
+// used when the client accepts any media type.
+type codecs []mediaCodec
+
+func defaultCodecs() codecs {
+	return codecs{
+		{"application/json", JSONCodec{}},
+		{"application/xml", XMLCodec{}},
+		{"text/xml", XMLCodec{}},
+		{"application/yaml", YAMLCodec{}},
+		{"application/x-yaml", YAMLCodec{}},
+		{"text/yaml", YAMLCodec{}},
+		{"application/msgpack", MsgpackCodec{}},
+		{"application/x-msgpack", MsgpackCodec{}},
+		{"application/vnd.msgpack", MsgpackCodec{}},
+		{"application/cbor", CBORCodec{}},
+		{"application/protobuf", ProtobufCodec{}},
+		{"application/x-protobuf", ProtobufCodec{}},
+	}
+}
+
+// RegisterCodec adds or replaces the codec of a media type, such as
+// RegisterCodec("application/vnd.company+json", JSONCodec{}).
+func (a *App) RegisterCodec(mediaType string, c Codec) {
+	mediaType = strings.ToLower(mediaType)
+
+	for i, mc := range a.codecs {
+		if mc.mediaType == mediaType {
+			a.codecs[i].codec = c
+			return
+		}
+	}
+
+	a.codecs = append(a.codecs, mediaCodec{mediaType, c})
+}
+
+func (cs codecs) get(mediaType string) Codec {
+	for _, mc := range cs {
+		if mc.mediaType == mediaType {
+			return mc.codec
+		}
+	}
+	return nil
+}
+
+// negotiate returns the media type and codec of the Accept header that
+// can encode v, the first codec if the header is empty. A media type has the
+// quality of the most specific range matching it, the codecs registered first
+// win ties so that JSON is preferred. Browsers, which accept text/html first
+// and XML before */*, get the first codec too.
+func (cs codecs) negotiate(accept string, v interface{}) (string, Codec, bool) {
+	canEncode := func(c Codec) bool {
+		e, ok := c.(interface{ CanEncode(v interface{}) bool })
+		return !ok || e.CanEncode(v)
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		accept = "*/*"
+	}
+
+	ranges := parseAccept(accept)
+
+	maxQ := 0.0
+	for _, r := range ranges {
+		maxQ = max(maxQ, r.q)
+	}
+	browser := maxQ > 0 && cs.get("text/html") == nil && quality(ranges, "text/html") == maxQ
+
+	var (
+		best  *mediaCodec
+		bestQ float64
+	)
+	for i, mc := range cs {
+		q := quality(ranges, mc.mediaType)
+		if browser && q > 0 {
+			q = maxQ
+		}
+		if q > bestQ && canEncode(mc.codec) {
+			best, bestQ = &cs[i], q
+		}
+	}
+
+	if best == nil {
+		return "", nil, false
+	}
+
+	return best.mediaType, best.codec, true
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// specificity ranks */* below type/* below a full media type.
+func (r acceptRange) specificity() int {
+	switch {
+	case r.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(r.mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (r acceptRange) matches(mediaType string) bool {
+	if r.mediaType == "*/*" || r.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(r.mediaType, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// quality returns the quality of the most specific range matching the media type, 0 if none does.
+func quality(ranges []acceptRange, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		if r.matches(mediaType) && r.specificity() > specificity {
+			q, specificity = r.q, r.specificity()
+		}
+	}
+	return q
+}
+
+// parseAccept returns the media ranges of the Accept header, including the
+// refused ones of quality 0.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+
+	return ranges
+}
+
+// Respond writes v with the status, encoded with the codec of the media type
+// negotiated from the Accept header, JSON by default. It returns a 406 HTTPError
+// if no registered codec is acceptable. The value is encoded before anything is
+// written, so an encoding error can still be answered with an error response.
+func (c *Context) Respond(status int, v interface{}) error {
+	mediaType, cd, ok := c.codecs().negotiate(c.GetHeader("Accept"), v)
+	if !ok {
+		return &HTTPError{Status: http.StatusNotAcceptable, Message: "none of the accepted media types can be produced"}
+	}
+
+	contentType := mediaType
+	if mediaType == "application/json" || strings.HasPrefix(mediaType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+
+	if status == http.StatusNoContent || status == http.StatusNotModified || c.Request.Method == http.MethodHead {
+		c.Header("Content-Type", contentType)
+		c.Status(status)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := cd.Encode(&buf, v); err != nil {
+		return fmt.Errorf("encoding %s: %w", mediaType, err)
+	}
+
+	c.Data(status, contentType, buf.Bytes())
+
+	return nil
+}
+
+// Decode decodes the request body into v with the codec of the Content-Type,
+// JSON if it is not set, and validates it with the binding tags.
+// It returns a 415 HTTPError if no codec is registered for the Content-Type,
+// and a 400 HTTPError if the body is invalid.
+func (c *Context) Decode(v interface{}) error {
+	if err := c.decodeBody(v); err != nil {
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(v)
+}
+
+// decodeBody decodes the request body into v without validating it.
+func (c *Context) decodeBody(v interface{}) error {
+	mediaType := "application/json"
+	if ct := c.GetHeader("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return badRequest(fmt.Sprintf("invalid Content-Type: %v", err))
+		}
+		mediaType = mt
+	}
+
+	cd := c.codecs().get(mediaType)
+	if cd == nil {
+		return &HTTPError{Status: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("unsupported media type %s", mediaType)}
+	}
+
+	if err := cd.Decode(c.Request.Body, v); err != nil {
+		return bindError(err)
+	}
+
+	return nil
+}
+
+// isForm reports whether the request body is a form, bound with the
+// `form` tags rather than decoded by a codec.
+func (c *Context) isForm() bool {
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Context) codecs() codecs {
+	if c.app == nil {
+		return defaultCodecs()
+	}
+	return c.app.codecs
+}
@@ -0,0 +1,91 @@
+package webber_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+type item struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+}
+
+func TestRespondNegotiation(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.GetE("/item", func(c *webber.Context) error {
+		return c.Respond(http.StatusOK, item{Name: "a"})
+	})
+
+	for accept, want := range map[string]string{
+		"":                               "application/json; charset=utf-8",
+		"*/*":                            "application/json; charset=utf-8",
+		"application/xml":                "application/xml",
+		"application/xml, */*":           "application/json; charset=utf-8",
+		"application/xml, */*;q=0.5":     "application/xml",
+		"application/*;q=0.5, text/yaml": "text/yaml; charset=utf-8",
+		"*/*, application/json;q=0":      "application/xml",
+		"application/yaml;q=0.8, text/*": "text/xml; charset=utf-8",
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": "application/json; charset=utf-8",
+	} {
+		app.Client().Get("/item").WithHeader("Accept", accept).Do().
+			Status(http.StatusOK).
+			Header("Content-Type", want)
+	}
+
+	app.Client().Get("/item").WithHeader("Accept", "image/png").Do().Status(http.StatusNotAcceptable)
+}
+
+func TestRespondEncodeError(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.GetE("/chan", func(c *webber.Context) error {
+		return c.Respond(http.StatusOK, map[string]interface{}{"a": make(chan int)})
+	})
+
+	// The value is encoded before the status is written
+	app.Client().Get("/chan").Do().
+		Status(http.StatusInternalServerError).
+		Header("Content-Type", "application/problem+json")
+}
+
+func TestRespondMapXML(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.GetE("/map", func(c *webber.Context) error {
+		return c.Respond(http.StatusOK, []map[string]string{{"a": "b"}})
+	})
+
+	// encoding/xml cannot encode maps, the next acceptable media type is used
+	app.Client().Get("/map").WithHeader("Accept", "application/xml, application/yaml;q=0.5").Do().
+		Status(http.StatusOK).
+		Header("Content-Type", "application/yaml")
+	app.Client().Get("/map").WithHeader("Accept", "application/xml").Do().
+		Status(http.StatusNotAcceptable)
+}
+
+type person struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+func TestYAMLFieldNames(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.PostE("/people", func(c *webber.Context) error {
+		var p person
+		if err := c.Decode(&p); err != nil {
+			return err
+		}
+		return c.Respond(http.StatusOK, p)
+	})
+
+	app.Client().Post("/people").
+		WithHeader("Accept", "application/yaml").
+		WithBody("application/yaml", strings.NewReader("first_name: ann\nlast_name: \"true\"\n")).Do().
+		Status(http.StatusOK).
+		BodyContains("first_name: ann\nlast_name: \"true\"\n")
+}
@@ -1,8 +1,99 @@
 package config
 
+import (
+	"strconv"
+	"time"
+)
+
 type Config interface {
 	GetString(key, defaultValue string) string
 	GetInt(key string, defaultValue int) (int, error)
 	GetFloat64(key string, defaultValue float64) (float64, error)
 	GetBool(key string, defaultValue bool) (bool, error)
 }
+
+// Lister is implemented by configs that can list the keys they define.
+type Lister interface {
+	Keys() []string
+}
+
+// DurationGetter is implemented by configs that parse durations themselves.
+type DurationGetter interface {
+	GetDuration(key string, defaultValue time.Duration) (time.Duration, error)
+}
+
+// GetDuration returns the value of the key parsed as time.Duration, plain
+// integers are interpreted as seconds. The configs that are not a
+// DurationGetter are read with GetString, an empty value is not set.
+func GetDuration(c Config, key string, defaultValue time.Duration) (time.Duration, error) {
+	if d, ok := c.(DurationGetter); ok {
+		return d.GetDuration(key, defaultValue)
+	}
+
+	return getDuration(func(key string) (string, bool) {
+		v := c.GetString(key, "")
+		return v, v != ""
+	}, key, defaultValue)
+}
+
+// lookupFunc returns the raw value for the given key and whether it is set.
+type lookupFunc func(key string) (string, bool)
+
+func getString(lookup lookupFunc, key, defaultValue string) string {
+	v, ok := lookup(key)
+	if ok {
+		return v
+	}
+	return defaultValue
+}
+
+func getInt(lookup lookupFunc, key string, defaultValue int) (int, error) {
+	v, ok := lookup(key)
+	if ok {
+		value, err := strconv.Atoi(v)
+		if err != nil {
+			return defaultValue, err
+		}
+		return value, nil
+	}
+	return defaultValue, nil
+}
+
+func getFloat64(lookup lookupFunc, key string, defaultValue float64) (float64, error) {
+	v, ok := lookup(key)
+	if ok {
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return defaultValue, err
+		}
+		return value, nil
+	}
+	return defaultValue, nil
+}
+
+func getBool(lookup lookupFunc, key string, defaultValue bool) (bool, error) {
+	v, ok := lookup(key)
+	if ok {
+		value, err := strconv.ParseBool(v)
+		if err != nil {
+			return defaultValue, err
+		}
+		return value, nil
+	}
+	return defaultValue, nil
+}
+
+func getDuration(lookup lookupFunc, key string, defaultValue time.Duration) (time.Duration, error) {
+	v, ok := lookup(key)
+	if ok {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+		value, err := time.ParseDuration(v)
+		if err != nil {
+			return defaultValue, err
+		}
+		return value, nil
+	}
+	return defaultValue, nil
+}
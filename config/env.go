@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"strconv"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,11 +20,26 @@ const (
 )
 
 type EnvLoader struct {
-	logger logger
+	logger     logger
+	configPath string
+
+	mu sync.Mutex
+	// keys defined in the loaded env files
+	keys []string
+	// variables set in the process environment before loading the env files,
+	// reloading never overrides them
+	processEnv map[string]string
+
+	subscribers
 }
 
 func New(configPath string, logger logger) Config {
-	cfg := &EnvLoader{logger: logger}
+	cfg := &EnvLoader{logger: logger, configPath: configPath, processEnv: map[string]string{}}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			cfg.processEnv[k] = v
+		}
+	}
 	cfg.load(configPath)
 	return cfg
 }
@@ -28,8 +47,8 @@ func New(configPath string, logger logger) Config {
 // Load loads the environment variables from the given configPath
 func (e *EnvLoader) load(configPath string) {
 	var (
-		defaultFile  = configPath + defaultFile
-		overrideFile = configPath + defaultOverrideFile
+		defaultFile  = filepath.Join(configPath, defaultFile)
+		overrideFile = filepath.Join(configPath, defaultOverrideFile)
 		env          = e.GetString("APP_ENV", "")
 	)
 
@@ -41,10 +60,11 @@ func (e *EnvLoader) load(configPath string) {
 		e.logger.Warnf("No %s file found", defaultFile)
 	} else {
 		e.logger.Infof("Loaded %s", defaultFile)
+		e.addKeys(defaultFile)
 	}
 
 	if env != "" {
-		overrideFile = fmt.Sprintf("%s/.%s.env", configPath, env)
+		overrideFile = filepath.Join(configPath, fmt.Sprintf(".%s.env", env))
 	}
 
 	err = godotenv.Overload(overrideFile)
@@ -55,57 +75,81 @@ func (e *EnvLoader) load(configPath string) {
 		e.logger.Warnf("No %s file found", overrideFile)
 	} else {
 		e.logger.Infof("Loaded %s", overrideFile)
+		e.addKeys(overrideFile)
+	}
+}
+
+func (e *EnvLoader) addKeys(file string) {
+	values, err := godotenv.Read(file)
+	if err != nil {
+		return
+	}
+	for k := range values {
+		if !slices.Contains(e.keys, k) {
+			e.keys = append(e.keys, k)
+		}
+	}
+	slices.Sort(e.keys)
+}
+
+// Keys returns the keys defined in the loaded env files.
+func (e *EnvLoader) Keys() []string {
+	return slices.Clone(e.keys)
+}
+
+// ProcessEnviron returns the process environment without the variables
+// loaded from the env files, as it was before loading them. It is the
+// environment of the processes that load the env files themselves.
+func (e *EnvLoader) ProcessEnviron() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var env []string
+	for _, kv := range os.Environ() {
+		k, _, _ := strings.Cut(kv, "=")
+		if slices.Contains(e.keys, k) {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	// The override file may have replaced process variables
+	for _, k := range e.keys {
+		if v, ok := e.processEnv[k]; ok {
+			env = append(env, k+"="+v)
+		}
 	}
+
+	return env
 }
 
 // GetString returns the env variable for the given key
 // and falls back to the given defaultValue if not set
 func (e *EnvLoader) GetString(key, defaultValue string) string {
-	v, ok := os.LookupEnv(key)
-	if ok {
-		return v
-	}
-	return defaultValue
+	return getString(os.LookupEnv, key, defaultValue)
 }
 
 // GetInt returns the env variable (parsed as integer) for
 // the given key and falls back to the given defaultValue if not set
 func (e *EnvLoader) GetInt(key string, defaultValue int) (int, error) {
-	v, ok := os.LookupEnv(key)
-	if ok {
-		value, err := strconv.Atoi(v)
-		if err != nil {
-			return defaultValue, err
-		}
-		return value, nil
-	}
-	return defaultValue, nil
+	return getInt(os.LookupEnv, key, defaultValue)
 }
 
 // GetFloat64 returns the env variable (parsed as float64) for
 // the given key and falls back to the given defaultValue if not set
 func (e *EnvLoader) GetFloat64(key string, defaultValue float64) (float64, error) {
-	v, ok := os.LookupEnv(key)
-	if ok {
-		value, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return defaultValue, err
-		}
-		return value, nil
-	}
-	return defaultValue, nil
+	return getFloat64(os.LookupEnv, key, defaultValue)
 }
 
 // GetBool returns the env variable (parsed as bool) for
 // the given key and falls back to the given defaultValue if not set
 func (e *EnvLoader) GetBool(key string, defaultValue bool) (bool, error) {
-	v, ok := os.LookupEnv(key)
-	if ok {
-		value, err := strconv.ParseBool(v)
-		if err != nil {
-			return defaultValue, err
-		}
-		return value, nil
-	}
-	return defaultValue, nil
+	return getBool(os.LookupEnv, key, defaultValue)
+}
+
+// GetDuration returns the env variable (parsed as time.Duration) for
+// the given key and falls back to the given defaultValue if not set.
+// Plain integers are interpreted as seconds.
+func (e *EnvLoader) GetDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	return getDuration(os.LookupEnv, key, defaultValue)
 }
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// MapLoader is a Config backed by an in-memory map instead of the process
+// environment. It is useful for libraries and tests.
+type MapLoader struct {
+	mu     sync.RWMutex
+	values map[string]string
+
+	subscribers
+}
+
+// NewMap returns a Config that reads values from the given map.
+// The map is copied, later changes to it are not visible to the Config.
+func NewMap(values map[string]string) Config {
+	cfg := &MapLoader{values: make(map[string]string, len(values))}
+	for k, v := range values {
+		cfg.values[k] = v
+	}
+	return cfg
+}
+
+// Keys returns the keys defined in the map.
+func (m *MapLoader) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (m *MapLoader) lookup(key string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set sets the value of key and notifies the subscribers if it changed.
+func (m *MapLoader) Set(key, value string) {
+	m.mu.Lock()
+	old, ok := m.values[key]
+	m.values[key] = value
+	m.mu.Unlock()
+
+	if ok && old == value {
+		return
+	}
+
+	oldValues := map[string]string{}
+	if ok {
+		oldValues[key] = old
+	}
+	m.notify(oldValues, map[string]string{key: value})
+}
+
+// GetString returns the value for the given key
+// and falls back to the given defaultValue if not set
+func (m *MapLoader) GetString(key, defaultValue string) string {
+	return getString(m.lookup, key, defaultValue)
+}
+
+// GetInt returns the value (parsed as integer) for
+// the given key and falls back to the given defaultValue if not set
+func (m *MapLoader) GetInt(key string, defaultValue int) (int, error) {
+	return getInt(m.lookup, key, defaultValue)
+}
+
+// GetFloat64 returns the value (parsed as float64) for
+// the given key and falls back to the given defaultValue if not set
+func (m *MapLoader) GetFloat64(key string, defaultValue float64) (float64, error) {
+	return getFloat64(m.lookup, key, defaultValue)
+}
+
+// GetBool returns the value (parsed as bool) for
+// the given key and falls back to the given defaultValue if not set
+func (m *MapLoader) GetBool(key string, defaultValue bool) (bool, error) {
+	return getBool(m.lookup, key, defaultValue)
+}
+
+// GetDuration returns the value (parsed as time.Duration) for
+// the given key and falls back to the given defaultValue if not set.
+// Plain integers are interpreted as seconds.
+func (m *MapLoader) GetDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	return getDuration(m.lookup, key, defaultValue)
+}
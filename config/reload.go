@@ -0,0 +1,223 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// ChangeFunc is called with the old and new values of the keys that changed.
+type ChangeFunc func(old, new map[string]string)
+
+// Reloader is implemented by configs that can be reloaded at runtime.
+type Reloader interface {
+	Reload() error
+}
+
+// Notifier is implemented by configs that notify the changes of their values.
+type Notifier interface {
+	// OnChange subscribes fn to the changes of the given keys, or of all keys
+	// if none are given. It is called with the old and new values of the keys
+	// that changed, a removed key is absent from new.
+	OnChange(keys []string, fn ChangeFunc)
+}
+
+// OnChange subscribes fn to the changes of the keys if the config is a Notifier,
+// it reports whether it is.
+func OnChange(c Config, keys []string, fn ChangeFunc) bool {
+	n, ok := c.(Notifier)
+	if ok {
+		n.OnChange(keys, fn)
+	}
+	return ok
+}
+
+type subscription struct {
+	keys []string
+	fn   ChangeFunc
+}
+
+// subscribers keeps the OnChange subscriptions of a config.
+type subscribers struct {
+	subsMu sync.Mutex
+	subs   []subscription
+}
+
+// OnChange subscribes fn to the changes of the given keys, or of all keys
+// if none are given.
+func (s *subscribers) OnChange(keys []string, fn ChangeFunc) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	s.subs = append(s.subs, subscription{keys: keys, fn: fn})
+}
+
+// notify calls the subscribers whose keys changed between old and new.
+func (s *subscribers) notify(old, new map[string]string) {
+	s.subsMu.Lock()
+	subs := slices.Clone(s.subs)
+	s.subsMu.Unlock()
+
+	for _, sub := range subs {
+		keys := sub.keys
+		if len(keys) == 0 {
+			keys = make([]string, 0, len(old)+len(new))
+			for k := range old {
+				keys = append(keys, k)
+			}
+			for k := range new {
+				if _, ok := old[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+		}
+
+		changedOld, changedNew := map[string]string{}, map[string]string{}
+		for _, k := range keys {
+			ov, oldOK := old[k]
+			nv, newOK := new[k]
+			if oldOK == newOK && ov == nv {
+				continue
+			}
+			if oldOK {
+				changedOld[k] = ov
+			}
+			if newOK {
+				changedNew[k] = nv
+			}
+		}
+
+		if len(changedOld) > 0 || len(changedNew) > 0 {
+			sub.fn(changedOld, changedNew)
+		}
+	}
+}
+
+// files returns the env files in loading order, the later ones take precedence.
+func (e *EnvLoader) files() []string {
+	overrideFile := filepath.Join(e.configPath, defaultOverrideFile)
+	if env := e.GetString("APP_ENV", ""); env != "" {
+		overrideFile = filepath.Join(e.configPath, fmt.Sprintf(".%s.env", env))
+	}
+
+	return []string{filepath.Join(e.configPath, defaultFile), overrideFile}
+}
+
+// Reload reads the env files again and notifies the subscribers of the keys that changed.
+// Variables set in the process environment before the initial load are left untouched,
+// and variables removed from the env files are unset.
+func (e *EnvLoader) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	values := map[string]string{}
+	for _, file := range e.files() {
+		fileValues, err := godotenv.Read(file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("error loading %s: %w", file, err)
+		}
+		maps.Copy(values, fileValues)
+	}
+
+	keys := slices.Clone(e.keys)
+	for k := range values {
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+
+	old := e.snapshot(keys)
+
+	for _, k := range e.keys {
+		if _, ok := values[k]; !ok && !e.isProcessKey(k) {
+			os.Unsetenv(k)
+		}
+	}
+	for k, v := range values {
+		if !e.isProcessKey(k) {
+			os.Setenv(k, v)
+		}
+	}
+
+	e.keys = e.keys[:0]
+	for k := range values {
+		e.keys = append(e.keys, k)
+	}
+	slices.Sort(e.keys)
+
+	e.logger.Infof("Reloaded config")
+
+	e.notify(old, e.snapshot(keys))
+
+	return nil
+}
+
+func (e *EnvLoader) isProcessKey(k string) bool {
+	_, ok := e.processEnv[k]
+	return ok
+}
+
+// snapshot returns the current values of the given keys, and of the subscribed keys.
+func (e *EnvLoader) snapshot(keys []string) map[string]string {
+	e.subsMu.Lock()
+	for _, sub := range e.subs {
+		keys = append(keys, sub.keys...)
+	}
+	e.subsMu.Unlock()
+
+	values := map[string]string{}
+	for _, k := range keys {
+		if v, ok := os.LookupEnv(k); ok {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// Watch reloads the config whenever one of the env files is modified,
+// checking their modification time every interval until ctx is done.
+func (e *EnvLoader) Watch(ctx context.Context, interval time.Duration) {
+	modTimes := e.modTimes()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := e.modTimes()
+			if maps.Equal(current, modTimes) {
+				continue
+			}
+			modTimes = current
+
+			if err := e.Reload(); err != nil {
+				e.logger.Errorf("Error reloading config: %v", err)
+			}
+		}
+	}
+}
+
+func (e *EnvLoader) modTimes() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	for _, file := range e.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
@@ -0,0 +1,117 @@
+package config_test
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+)
+
+// writeEnv writes the .env file of dir and unsets its keys at the end of the test.
+func writeEnv(t *testing.T, dir, content string, keys ...string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, k := range keys {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+type change struct {
+	old, new map[string]string
+}
+
+func TestEnvLoaderReload(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("RELOAD_TEST_PROCESS", "process")
+	writeEnv(t, dir, "RELOAD_TEST_A=1\nRELOAD_TEST_B=2\nRELOAD_TEST_D=5\nRELOAD_TEST_PROCESS=file\n",
+		"RELOAD_TEST_A", "RELOAD_TEST_B", "RELOAD_TEST_C", "RELOAD_TEST_D")
+
+	cfg := config.New(dir, log.NewWithZap(zap.NewNop()))
+
+	var all, unchanged []change
+	config.OnChange(cfg, nil, func(old, new map[string]string) {
+		all = append(all, change{old, new})
+	})
+	config.OnChange(cfg, []string{"RELOAD_TEST_A", "RELOAD_TEST_PROCESS"}, func(old, new map[string]string) {
+		unchanged = append(unchanged, change{old, new})
+	})
+
+	writeEnv(t, dir, "RELOAD_TEST_A=1\nRELOAD_TEST_B=3\nRELOAD_TEST_C=4\nRELOAD_TEST_PROCESS=changed\n")
+	if err := cfg.(config.Reloader).Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 1 {
+		t.Fatalf("expected one notification, got %v", all)
+	}
+	if want := map[string]string{"RELOAD_TEST_B": "2", "RELOAD_TEST_D": "5"}; !maps.Equal(all[0].old, want) {
+		t.Errorf("expected the old values %v, got %v", want, all[0].old)
+	}
+	if want := map[string]string{"RELOAD_TEST_B": "3", "RELOAD_TEST_C": "4"}; !maps.Equal(all[0].new, want) {
+		t.Errorf("expected the new values %v, got %v", want, all[0].new)
+	}
+	if len(unchanged) != 0 {
+		t.Errorf("expected no notification for the unchanged keys, got %v", unchanged)
+	}
+
+	if v := cfg.GetString("RELOAD_TEST_PROCESS", ""); v != "process" {
+		t.Errorf("expected the process environment to be kept, got %q", v)
+	}
+	if _, ok := os.LookupEnv("RELOAD_TEST_D"); ok {
+		t.Error("expected the removed key to be unset")
+	}
+	if v := cfg.GetString("RELOAD_TEST_C", ""); v != "4" {
+		t.Errorf("expected the added key to be set, got %q", v)
+	}
+}
+
+func TestEnvLoaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeEnv(t, dir, "WATCH_TEST_A=1\n", "WATCH_TEST_A")
+
+	cfg := config.New(dir, log.NewWithZap(zap.NewNop()))
+
+	changes := make(chan change, 1)
+	config.OnChange(cfg, []string{"WATCH_TEST_A"}, func(old, new map[string]string) {
+		changes <- change{old, new}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cfg.(*config.EnvLoader).Watch(ctx, 10*time.Millisecond)
+
+	writeEnv(t, dir, "WATCH_TEST_A=2\n")
+
+	// Watch may read the modification time after the write, it is moved
+	// forward until the reload since it may not change within the resolution
+	// of the file system either
+	deadline := time.After(5 * time.Second)
+	for i := 1; ; i++ {
+		later := time.Now().Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, ".env"), later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case c := <-changes:
+			if c.old["WATCH_TEST_A"] != "1" || c.new["WATCH_TEST_A"] != "2" {
+				t.Errorf("expected WATCH_TEST_A to change from 1 to 2, got %v", c)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("expected the modified file to be reloaded")
+		}
+	}
+}
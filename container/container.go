@@ -4,6 +4,7 @@ import (
 	"github.com/xbmlz/webber/config"
 	"github.com/xbmlz/webber/datasource/db"
 	"github.com/xbmlz/webber/datasource/redis"
+	"github.com/xbmlz/webber/datasource/storage"
 	"github.com/xbmlz/webber/log"
 )
 
@@ -12,29 +13,71 @@ type Container struct {
 
 	Config config.Config
 
-	DB    *db.DB
-	Redis *redis.Redis
+	DB      *db.DB
+	Redis   *redis.Redis
+	Storage storage.Storage
 }
 
-func New(cfg config.Config) *Container {
+// Option configures how a Container is initialized.
+type Option func(*options)
+
+type options struct {
+	logger       log.Logger
+	withoutDB    bool
+	withoutRedis bool
+}
+
+// WithLogger sets the logger instead of building one from the config.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithoutDB skips the database connection even if it is configured.
+func WithoutDB() Option {
+	return func(o *options) {
+		o.withoutDB = true
+	}
+}
+
+// WithoutRedis skips the redis connection even if it is configured.
+func WithoutRedis() Option {
+	return func(o *options) {
+		o.withoutRedis = true
+	}
+}
+
+func New(cfg config.Config, opts ...Option) *Container {
 	if cfg == nil {
 		return &Container{}
 	}
 
-	c := &Container{}
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	c := &Container{Logger: o.logger}
 
-	c.init(cfg)
+	c.init(cfg, o)
 	return c
 }
 
-func (c *Container) init(cfg config.Config) {
+func (c *Container) init(cfg config.Config, o *options) {
 	if c.Logger == nil {
 		c.Logger = log.NewWithConfg(cfg)
 	}
 
 	c.Config = cfg
 
-	c.Redis = redis.New(cfg, c.Logger)
+	if !o.withoutRedis {
+		c.Redis = redis.New(cfg, c.Logger)
+	}
+
+	if !o.withoutDB {
+		c.DB = db.New(cfg, c.Logger)
+	}
 
-	c.DB = db.New(cfg, c.Logger)
+	c.Storage = storage.New(cfg, c.Logger)
 }
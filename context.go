@@ -1,6 +1,9 @@
 package webber
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/xbmlz/webber/container"
 )
@@ -8,4 +11,60 @@ import (
 type Context struct {
 	*container.Container
 	*gin.Context
+
+	// ctx is used instead of the request context when the Context
+	// is not bound to an HTTP request, such as in workers
+	ctx context.Context
+
+	// app is set for HTTP requests, it provides the codecs
+	app *App
+
+	// stream is the event stream started with SSE
+	stream *SSEStream
+}
+
+// Deadline implements context.Context.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Context == nil {
+		return c.base().Deadline()
+	}
+	return c.Context.Deadline()
+}
+
+// Done implements context.Context.
+func (c *Context) Done() <-chan struct{} {
+	if c.Context == nil {
+		return c.base().Done()
+	}
+	return c.Context.Done()
+}
+
+// Err implements context.Context.
+func (c *Context) Err() error {
+	if c.Context == nil {
+		return c.base().Err()
+	}
+	return c.Context.Err()
+}
+
+// Value implements context.Context.
+func (c *Context) Value(key any) any {
+	if c.Context == nil {
+		return c.base().Value(key)
+	}
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	// The values of the request context, such as the request ID
+	if c.Request != nil {
+		return c.Request.Context().Value(key)
+	}
+	return nil
+}
+
+func (c *Context) base() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
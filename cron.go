@@ -1,6 +1,9 @@
 package webber
 
 import (
+	"context"
+	"sync"
+
 	"github.com/robfig/cron/v3"
 	"github.com/xbmlz/webber/container"
 )
@@ -8,10 +11,39 @@ import (
 type crontab struct {
 	*cron.Cron
 	container *container.Container
+
+	mu   sync.Mutex
+	jobs []*cronJob
 }
 
 type CronFunc func(ctx *Context)
 
+// CronOption configures a cron job.
+type CronOption func(*cronJob)
+
+// WithCronName sets the name used to identify the job, it defaults to the spec.
+func WithCronName(name string) CronOption {
+	return func(j *cronJob) {
+		j.name = name
+	}
+}
+
+// WithCronSpecKey reads the spec from the given config key, falling back to the
+// spec passed to AddCronJob. The job is rescheduled when the config is reloaded.
+func WithCronSpecKey(key string) CronOption {
+	return func(j *cronJob) {
+		j.specKey = key
+	}
+}
+
+type cronJob struct {
+	id      cron.EntryID
+	name    string
+	spec    string
+	specKey string
+	fn      func()
+}
+
 func NewCron(c *container.Container) *crontab {
 	cron := cron.New()
 
@@ -20,3 +52,47 @@ func NewCron(c *container.Container) *crontab {
 		Cron:      cron,
 	}
 }
+
+// Shutdown stops the scheduler and waits for the running jobs to complete
+// or for the context to be done, whichever happens first.
+func (c *crontab) Shutdown(ctx context.Context) error {
+	done := c.Cron.Stop()
+
+	select {
+	case <-done.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reschedule replaces the schedule of the job, the previous
+// schedule is kept if the spec is invalid.
+func (c *crontab) reschedule(j *cronJob, spec string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
+
+	c.Remove(j.id)
+	j.id = c.Schedule(schedule, cron.FuncJob(j.fn))
+	j.spec = spec
+
+	return nil
+}
+
+// job returns the job with the given name, or nil.
+func (c *crontab) job(name string) *cronJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, j := range c.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
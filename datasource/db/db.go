@@ -1,6 +1,9 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -34,6 +37,8 @@ type Config struct {
 	LogLevel    string
 }
 
+var errNotConnected = errors.New("not connected to database")
+
 var errUnsupportedDialect = fmt.Errorf("unsupported db dialect; supported dialects are - mysql, postgres, sqlite")
 
 func New(config config.Config, logger datasource.Logger) *DB {
@@ -55,7 +60,7 @@ func New(config config.Config, logger datasource.Logger) *DB {
 	database := &DB{config: dbConfig, logger: logger}
 
 	database.DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger: gormLogger.Default.LogMode(parseLogLevel(dbConfig.LogLevel)),
+		Logger: newLogger(parseLogLevel(dbConfig.LogLevel)),
 	})
 	if err != nil {
 		logger.Errorf("failed to connect to database: %v", err)
@@ -75,6 +80,9 @@ func New(config config.Config, logger datasource.Logger) *DB {
 	// it is closed automatically.
 	sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConn)
 
+	// The pool sizes can be retuned while running by reloading the config
+	resizeOnChange(config, sqlDB, logger)
+
 	if err := sqlDB.Ping(); err != nil {
 		logger.Errorf("failed to ping database: %v", err)
 	}
@@ -84,6 +92,16 @@ func New(config config.Config, logger datasource.Logger) *DB {
 	return database
 }
 
+// resizeOnChange resizes the pool when its config changes.
+func resizeOnChange(c config.Config, sqlDB *sql.DB, logger datasource.Logger) {
+	config.OnChange(c, []string{"DB_MAX_OPEN_CONNECTION", "DB_MAX_IDLE_CONNECTION"}, func(_, _ map[string]string) {
+		poolConfig := getConfig(c)
+		sqlDB.SetMaxIdleConns(poolConfig.MaxIdleConn)
+		sqlDB.SetMaxOpenConns(poolConfig.MaxOpenConn)
+		logger.Infof("database pool resized to %d open and %d idle connections", poolConfig.MaxOpenConn, poolConfig.MaxIdleConn)
+	})
+}
+
 func parseLogLevel(level string) gormLogger.LogLevel {
 	switch level {
 	case "silent":
@@ -139,3 +157,31 @@ func getConfig(c config.Config) *Config {
 		Port:        port,
 	}
 }
+
+// Ping checks that the database is reachable.
+func (d *DB) Ping(ctx context.Context) error {
+	if d.DB == nil {
+		return errNotConnected
+	}
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the underlying connection pool.
+func (d *DB) Close() error {
+	if d.DB == nil {
+		return nil
+	}
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
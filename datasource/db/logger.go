@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	stdlog "log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xbmlz/webber/log"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// loggerFile is skipped, along with the GORM files, to find the caller of a query.
+var loggerFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// newLogger returns a logger writing like the default GORM logger, the
+// queries are prefixed with the request ID of their context.
+func newLogger(level gormLogger.LogLevel) gormLogger.Interface {
+	return requestLogger{gormLogger.New(callerWriter{stdlog.New(os.Stdout, "\r\n", stdlog.LstdFlags)}, gormLogger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      level,
+		Colorful:      true,
+	})}
+}
+
+type requestLogger struct {
+	gormLogger.Interface
+}
+
+func (l requestLogger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	return requestLogger{l.Interface.LogMode(level)}
+}
+
+func (l requestLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	id := log.RequestID(ctx)
+	if id == "" {
+		l.Interface.Trace(ctx, begin, fc, err)
+		return
+	}
+
+	l.Interface.Trace(ctx, begin, func() (string, int64) {
+		sql, rows := fc()
+		return "[request " + id + "] " + sql, rows
+	}, err)
+}
+
+// callerWriter replaces the file logged by GORM, which would be this logger,
+// by the caller of the query.
+type callerWriter struct {
+	gormLogger.Writer
+}
+
+func (w callerWriter) Printf(format string, args ...interface{}) {
+	if len(args) > 0 {
+		args[0] = caller()
+	}
+	w.Writer.Printf(format, args...)
+}
+
+func caller() string {
+	pcs := [16]uintptr{}
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") && frame.File != loggerFile {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
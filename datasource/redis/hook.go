@@ -10,6 +10,7 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"github.com/xbmlz/webber/datasource"
+	"github.com/xbmlz/webber/log"
 )
 
 type redisHook struct {
@@ -18,9 +19,10 @@ type redisHook struct {
 }
 
 type QueryLog struct {
-	Query    string      `json:"query"`
-	Duration int64       `json:"duration"`
-	Args     interface{} `json:"args,omitempty"`
+	Query     string      `json:"query"`
+	Duration  int64       `json:"duration"`
+	Args      interface{} `json:"args,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 func (ql *QueryLog) PrettyPrint(writer io.Writer) {
@@ -59,15 +61,22 @@ func (ql *QueryLog) String() string {
 	}
 }
 
-func (r *redisHook) sendOperationStats(start time.Time, query string, args ...interface{}) {
+func (r *redisHook) sendOperationStats(ctx context.Context, start time.Time, query string, args ...interface{}) {
 	duration := time.Since(start).Microseconds()
 
-	r.logger.Debug(&QueryLog{
-		Query:    query,
-		Duration: duration,
-		Args:     args,
-	})
+	ql := &QueryLog{
+		Query:     query,
+		Duration:  duration,
+		Args:      args,
+		RequestID: log.RequestID(ctx),
+	}
+
+	if ql.RequestID != "" {
+		r.logger.Debugf("[request %s] %v", ql.RequestID, ql)
+		return
+	}
 
+	r.logger.Debug(ql)
 }
 
 // DialHook implements the redis.DialHook interface.
@@ -80,7 +89,7 @@ func (r *redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 	return func(ctx context.Context, cmd redis.Cmder) error {
 		start := time.Now()
 		err := next(ctx, cmd)
-		r.sendOperationStats(start, cmd.Name(), cmd.Args()...)
+		r.sendOperationStats(ctx, start, cmd.Name(), cmd.Args()...)
 
 		return err
 	}
@@ -91,7 +100,7 @@ func (r *redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.Pr
 	return func(ctx context.Context, cmds []redis.Cmder) error {
 		start := time.Now()
 		err := next(ctx, cmds)
-		r.sendOperationStats(start, "pipeline", cmds[:len(cmds)-1])
+		r.sendOperationStats(ctx, start, "pipeline", cmds[:len(cmds)-1])
 
 		return err
 	}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tempPrefix is the prefix of the files being written, they are not listed.
+const tempPrefix = ".tmp-"
+
+// Local stores the objects as files under a root directory.
+type Local struct {
+	root   string
+	signer *URLSigner
+}
+
+// NewLocal returns a storage in the root directory, it is created if needed.
+// SignedURL returns ErrNotSupported if signer is nil.
+func NewLocal(root string, signer *URLSigner) (*Local, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Local{root: root, signer: signer}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) || strings.HasPrefix(key[strings.LastIndex(key, "/")+1:], tempPrefix) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object to a temporary file renamed once complete,
+// so that readers never see a partial object.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, _ PutOptions) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, contextReader{ctx, r}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (l *Local) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, ErrNotExist
+	}
+
+	return f, nil
+}
+
+func (l *Local) Delete(_ context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	// Only the directory of the prefix is walked
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		if !ValidKey(prefix[:i]) {
+			return nil, ErrInvalidKey
+		}
+		dir = filepath.Join(l.root, filepath.FromSlash(prefix[:i]))
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileInfo(key, info))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+func (l *Local) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotExist
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return fileInfo(key, info), nil
+}
+
+func (l *Local) SignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	if l.signer == nil {
+		return "", ErrNotSupported
+	}
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	return l.signer.Sign(key, expires), nil
+}
+
+func fileInfo(key string, info fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType(key),
+		ModTime:     info.ModTime(),
+	}
+}
+
+// contextReader stops reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// VerifyURL checks the query of a URL returned by SignedURL.
+func (l *Local) VerifyURL(key string, query url.Values) error {
+	if l.signer == nil {
+		return ErrNotSupported
+	}
+	return l.signer.Verify(key, query)
+}
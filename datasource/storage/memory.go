@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory stores the objects in memory, it is meant for tests.
+type Memory struct {
+	signer *URLSigner
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// NewMemory returns an empty storage. SignedURL returns ErrNotSupported if signer is nil.
+func NewMemory(signer *URLSigner) *Memory {
+	return &Memory{signer: signer, objects: map[string]memoryObject{}}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	data, err := io.ReadAll(contextReader{ctx, r})
+	if err != nil {
+		return err
+	}
+
+	ct := opts.ContentType
+	if ct == "" {
+		ct = contentType(key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[key] = memoryObject{
+		data: data,
+		info: ObjectInfo{Key: key, Size: int64(len(data)), ContentType: ct, ModTime: time.Now()},
+	}
+
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(o.data)), nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, key)
+
+	return nil
+}
+
+func (m *Memory) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var objects []ObjectInfo
+	for key, o := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, o.info)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+func (m *Memory) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.objects[key]
+	if !ok {
+		return ObjectInfo{}, ErrNotExist
+	}
+
+	return o.info, nil
+}
+
+func (m *Memory) SignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	if m.signer == nil {
+		return "", ErrNotSupported
+	}
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	return m.signer.Sign(key, expires), nil
+}
+
+// VerifyURL checks the query of a URL returned by SignedURL.
+func (m *Memory) VerifyURL(key string, query url.Values) error {
+	if m.signer == nil {
+		return ErrNotSupported
+	}
+	return m.signer.Verify(key, query)
+}
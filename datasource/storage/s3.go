@@ -0,0 +1,511 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3PartSize is the size of the parts of the multipart uploads, the
+	// objects of unknown size are buffered one part at a time
+	s3PartSize = 5 << 20
+	// s3MaxPutSize is the largest object of a single PUT
+	s3MaxPutSize = 5 << 30
+	// s3MaxExpires is the longest validity of a presigned URL
+	s3MaxExpires = 7 * 24 * time.Hour
+
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+)
+
+// s3EmptyHash is the SHA-256 of an empty payload.
+var s3EmptyHash = hashHex(nil)
+
+type S3Config struct {
+	// Endpoint is the URL of the S3-compatible service, AWS if empty
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket in the path rather than in the host name,
+	// as expected by most S3-compatible services
+	PathStyle bool
+}
+
+// S3 stores the objects in a bucket of an S3-compatible service,
+// the requests are signed with AWS Signature Version 4.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3 returns a storage in the bucket of the config.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("'STORAGE_S3_BUCKET' is not provided")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+
+	return &S3{config: cfg, endpoint: u, client: &http.Client{}}, nil
+}
+
+// Put uploads the object in a single request if its size is known,
+// otherwise in parts of 5 MiB unless it fits in one.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	ct := opts.ContentType
+	if ct == "" {
+		ct = contentType(key)
+	}
+	header := http.Header{"Content-Type": {ct}}
+
+	if opts.Size > 0 && opts.Size <= s3MaxPutSize {
+		return s.close(s.do(ctx, http.MethodPut, key, nil, header, r, opts.Size, s3UnsignedPayload))
+	}
+
+	buf := make([]byte, s3PartSize)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s.close(s.do(ctx, http.MethodPut, key, nil, header, bytes.NewReader(buf[:n]), int64(n), hashHex(buf[:n])))
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.putMultipart(ctx, key, header, buf, r)
+}
+
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) putMultipart(ctx context.Context, key string, header http.Header, buf []byte, r io.Reader) (err error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil, 0, s3EmptyHash)
+	if err != nil {
+		return err
+	}
+
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := decodeXML(resp, &initiate); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			// The parts are kept by the bucket until the upload is aborted
+			_ = s.close(s.do(context.WithoutCancel(ctx), http.MethodDelete, key, url.Values{"uploadId": {initiate.UploadID}}, nil, nil, 0, s3EmptyHash))
+		}
+	}()
+
+	var parts []s3Part
+	n := len(buf)
+	for n > 0 {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(len(parts) + 1)},
+			"uploadId":   {initiate.UploadID},
+		}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, bytes.NewReader(buf[:n]), int64(n), hashHex(buf[:n]))
+		if err != nil {
+			return err
+		}
+		parts = append(parts, s3Part{PartNumber: len(parts) + 1, ETag: resp.Header.Get("ETag")})
+		if err := s.close(resp, nil); err != nil {
+			return err
+		}
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {initiate.UploadID}}, nil, bytes.NewReader(body), int64(len(body)), hashHex(body))
+	if err != nil {
+		return err
+	}
+
+	// The completion can fail after the response status is sent
+	var complete struct {
+		XMLName xml.Name
+		s3Error
+	}
+	if err := decodeXML(resp, &complete); err != nil {
+		return err
+	}
+	if complete.XMLName.Local == "Error" {
+		return &complete.s3Error
+	}
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil, 0, s3EmptyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := s.close(s.do(ctx, http.MethodDelete, key, nil, nil, nil, 0, s3EmptyHash))
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil, 0, s3EmptyHash)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string    `xml:"Key"`
+				LastModified time.Time `xml:"LastModified"`
+				Size         int64     `xml:"Size"`
+			} `xml:"Contents"`
+		}
+		if err := decodeXML(resp, &result); err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:         c.Key,
+				Size:        c.Size,
+				ContentType: contentType(c.Key),
+				ModTime:     c.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	if !ValidKey(key) {
+		return ObjectInfo{}, ErrInvalidKey
+	}
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, 0, s3EmptyHash)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ModTime:     modTime,
+	}, nil
+}
+
+// SignedURL returns a presigned GET URL, valid for at most 7 days.
+func (s *S3) SignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+	if expires <= 0 || expires > s3MaxExpires {
+		return "", fmt.Errorf("s3: signed URLs expire within %s", s3MaxExpires)
+	}
+
+	return s.presign(key, expires, time.Now()).String(), nil
+}
+
+func (s *S3) presign(key string, expires time.Duration, t time.Time) *url.URL {
+	u := s.url(key)
+
+	amzDate := t.UTC().Format(s3TimeFormat)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.config.AccessKey + "/" + s.scope(t)},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.FormatInt(int64(expires/time.Second), 10)},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+
+	signature := s.signature(t, strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n"))
+
+	query.Set("X-Amz-Signature", signature)
+	u.RawQuery = canonicalQuery(query)
+
+	return u
+}
+
+// url returns the URL of the key, of the bucket if key is empty.
+func (s *S3) url(key string) *url.URL {
+	u := *s.endpoint
+
+	p := strings.TrimSuffix(u.Path, "/")
+	if s.config.PathStyle {
+		p += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	if key != "" || !s.config.PathStyle {
+		p += "/" + key
+	}
+
+	u.Path = p
+	u.RawPath = awsEscape(p, false)
+	u.RawQuery = ""
+
+	return &u
+}
+
+// do sends a signed request, it returns an error if the response status is not successful.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := s.url(key)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if body != nil && size == 0 {
+		req.Body = http.NoBody
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	s.sign(req, u, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound && method != http.MethodPost {
+			return nil, ErrNotExist
+		}
+
+		e := &s3Error{StatusCode: resp.StatusCode}
+		_ = xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(e)
+
+		return nil, e
+	}
+
+	return resp, nil
+}
+
+// close closes the response of do.
+func (s *S3) close(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// sign adds the Authorization header of the request.
+func (s *S3) sign(req *http.Request, u *url.URL, payloadHash string, t time.Time) {
+	req.Header.Set("X-Amz-Date", t.UTC().Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": u.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	signature := s.signature(t, strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n"))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKey, s.scope(t), signedHeaders, signature))
+}
+
+func (s *S3) scope(t time.Time) string {
+	return t.UTC().Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// signature signs the canonical request with the key derived from the secret key.
+func (s *S3) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.UTC().Format(s3TimeFormat),
+		s.scope(t),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+func decodeXML(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// canonicalQuery encodes the query sorted by key as expected by the signature.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters,
+// and the slashes unless escapeSlash is set.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned by URLSigner.Verify for the URLs
+// that were not signed by the signer or have expired.
+var ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+
+// URLSigner signs the URLs of the drivers that do not sign their own,
+// the objects are served at the URL by webber's App.ServeStorage.
+type URLSigner struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewURLSigner returns a signer of the URLs under baseURL. A random secret is
+// used if it is empty, the URLs are then only valid for the running process.
+func NewURLSigner(baseURL, secret string) *URLSigner {
+	s := &URLSigner{baseURL: strings.TrimSuffix(baseURL, "/"), secret: []byte(secret)}
+	if len(s.secret) == 0 {
+		s.secret = make([]byte, 32)
+		_, _ = rand.Read(s.secret)
+	}
+	return s
+}
+
+// Sign returns the URL of the key, valid until it expires.
+func (s *URLSigner) Sign(key string, expires time.Duration) string {
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", s.signature(key, exp))
+
+	return s.baseURL + "/" + escapeKey(key) + "?" + q.Encode()
+}
+
+// Verify checks the expires and signature query parameters of the URL of the key.
+func (s *URLSigner) Verify(key string, query url.Values) error {
+	exp := query.Get("expires")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(s.signature(key, exp))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s *URLSigner) signature(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// escapeKey escapes the segments of the key for a URL path.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/datasource"
+)
+
+var (
+	// ErrNotExist is returned when an object does not exist.
+	ErrNotExist = errors.New("storage: object does not exist")
+	// ErrInvalidKey is returned for the keys that are empty, absolute or
+	// contain "." or ".." segments.
+	ErrInvalidKey = errors.New("storage: invalid key")
+	// ErrNotSupported is returned by SignedURL when the driver has no URL to sign.
+	ErrNotSupported = errors.New("storage: operation not supported")
+)
+
+// Storage stores objects by key, keys are slash separated paths such as "avatars/1.png".
+type Storage interface {
+	// Put writes the object, replacing any existing one. The reader is
+	// streamed to the storage.
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error
+	// Get returns the content of the object, it must be closed.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object, it does not fail if it does not exist.
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose key starts with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Stat returns the information of the object.
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// SignedURL returns a URL giving access to the object until it expires.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// PutOptions are the options of Storage.Put.
+type PutOptions struct {
+	// Size is the size of the content, 0 if it is not known
+	Size        int64
+	ContentType string
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+type Config struct {
+	Driver string
+	// Path is the root directory of the local driver
+	Path string
+	// URL is the base URL of the signed URLs of the local and memory drivers
+	URL    string
+	Secret string
+
+	S3 S3Config
+}
+
+// New returns the storage of STORAGE_DRIVER: local, memory or s3.
+// It returns nil if STORAGE_DRIVER is not set.
+func New(cfg config.Config, logger datasource.Logger) Storage {
+	storageConfig := getConfig(cfg)
+
+	var signer *URLSigner
+	if storageConfig.URL != "" {
+		if storageConfig.Secret == "" {
+			logger.Warnf("'STORAGE_SECRET' is not set, the signed URLs are invalidated on restart")
+		}
+		signer = NewURLSigner(storageConfig.URL, storageConfig.Secret)
+	}
+
+	switch storageConfig.Driver {
+	case "":
+		logger.Debugf("skipping storage initialization as 'STORAGE_DRIVER' is not provided")
+		return nil
+	case "local":
+		s, err := NewLocal(storageConfig.Path, signer)
+		if err != nil {
+			logger.Errorf("failed to initialize local storage: %v", err)
+			return nil
+		}
+		logger.Infof("storing files in %s", storageConfig.Path)
+		return s
+	case "memory":
+		return NewMemory(signer)
+	case "s3":
+		s, err := NewS3(storageConfig.S3)
+		if err != nil {
+			logger.Errorf("failed to initialize s3 storage: %v", err)
+			return nil
+		}
+		logger.Infof("storing files in s3 bucket %s", storageConfig.S3.Bucket)
+		return s
+	default:
+		logger.Errorf("unsupported storage driver %q; supported drivers are - local, memory, s3", storageConfig.Driver)
+		return nil
+	}
+}
+
+func getConfig(c config.Config) *Config {
+	pathStyle, _ := c.GetBool("STORAGE_S3_PATH_STYLE", c.GetString("STORAGE_S3_ENDPOINT", "") != "")
+
+	return &Config{
+		Driver: c.GetString("STORAGE_DRIVER", ""),
+		Path:   c.GetString("STORAGE_PATH", "./storage"),
+		URL:    c.GetString("STORAGE_URL", ""),
+		Secret: c.GetString("STORAGE_SECRET", ""),
+		S3: S3Config{
+			Endpoint:  c.GetString("STORAGE_S3_ENDPOINT", ""),
+			Region:    c.GetString("STORAGE_S3_REGION", "us-east-1"),
+			Bucket:    c.GetString("STORAGE_S3_BUCKET", ""),
+			AccessKey: c.GetString("STORAGE_S3_ACCESS_KEY", ""),
+			SecretKey: c.GetString("STORAGE_S3_SECRET_KEY", ""),
+			PathStyle: pathStyle,
+		},
+	}
+}
+
+// ValidKey reports whether the key can be stored by every driver.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// contentType returns the content type of the key by its extension.
+func contentType(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
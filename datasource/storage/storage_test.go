@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+)
+
+// testStorage checks the behaviour shared by every driver.
+func testStorage(t *testing.T, s Storage) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	keys := []string{"a/1.txt", "a/2 b+c.txt", "a/sub/3.txt", "b/1.png"}
+	for _, k := range keys {
+		if err := s.Put(ctx, k, strings.NewReader("hello "+k), PutOptions{}); err != nil {
+			t.Fatalf("put %s: %v", k, err)
+		}
+	}
+
+	for _, k := range []string{"", "/a", "../a", "a/./b", "a//b", "a\\b"} {
+		if err := s.Put(ctx, k, strings.NewReader(""), PutOptions{}); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("put %q: expected ErrInvalidKey, got %v", k, err)
+		}
+	}
+
+	objects, err := s.List(ctx, "a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := objectKeys(objects); got != "a/1.txt,a/2 b+c.txt,a/sub/3.txt" {
+		t.Errorf("list a/: got %s", got)
+	}
+
+	objects, err = s.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := objectKeys(objects); got != strings.Join(keys, ",") {
+		t.Errorf("list: got %s", got)
+	}
+
+	info, err := s.Stat(ctx, "b/1.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != "b/1.png" || info.Size != int64(len("hello b/1.png")) || info.ContentType != "image/png" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	r, err := s.Get(ctx, "a/2 b+c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(b) != "hello a/2 b+c.txt" {
+		t.Errorf("get: got %q, %v", b, err)
+	}
+
+	if err := s.Put(ctx, "a/1.txt", strings.NewReader("replaced"), PutOptions{Size: 8, ContentType: "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := s.Stat(ctx, "a/1.txt"); err != nil || info.Size != 8 {
+		t.Errorf("expected the replaced object, got %+v, %v", info, err)
+	}
+
+	if _, err := s.Stat(ctx, "a/missing"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("stat: expected ErrNotExist, got %v", err)
+	}
+	if _, err := s.Get(ctx, "a/missing"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("get: expected ErrNotExist, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Delete(ctx, "a/1.txt"); err != nil {
+			t.Errorf("delete: %v", err)
+		}
+	}
+	if _, err := s.Stat(ctx, "a/1.txt"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected the object to be deleted, got %v", err)
+	}
+}
+
+func objectKeys(objects []ObjectInfo) string {
+	keys := make([]string, len(objects))
+	for i, o := range objects {
+		keys[i] = o.Key
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStorage(t, s)
+
+	if _, err := s.SignedURL(context.Background(), "b/1.png", time.Minute); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without a signer, got %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStorage(t, NewMemory(nil))
+}
+
+func TestS3(t *testing.T) {
+	_, s := newFakeS3(t)
+
+	testStorage(t, s)
+}
+
+func TestSignedURL(t *testing.T) {
+	signer := NewURLSigner("https://example.com/files/", "secret")
+	s := NewMemory(signer)
+
+	u, err := s.SignedURL(context.Background(), "a dir/1.png", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "https://example.com/files/a%20dir/1.png?") {
+		t.Errorf("unexpected URL %s", u)
+	}
+
+	query := mustParseQuery(t, u)
+	if err := s.VerifyURL("a dir/1.png", query); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	if err := s.VerifyURL("a dir/2.png", query); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify another key: expected ErrInvalidSignature, got %v", err)
+	}
+	if err := NewMemory(NewURLSigner("/files", "other")).VerifyURL("a dir/1.png", query); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify another secret: expected ErrInvalidSignature, got %v", err)
+	}
+
+	query.Set("expires", "1")
+	if err := s.VerifyURL("a dir/1.png", query); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify a changed expiry: expected ErrInvalidSignature, got %v", err)
+	}
+
+	expired := mustParseQuery(t, signer.Sign("a dir/1.png", -time.Second))
+	if err := s.VerifyURL("a dir/1.png", expired); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify an expired URL: expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func mustParseQuery(t *testing.T, u string) url.Values {
+	t.Helper()
+
+	_, rawQuery, _ := strings.Cut(u, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+func TestValidKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"a":           true,
+		"a/b.txt":     true,
+		"a b/.hidden": true,
+		"":            false,
+		"/a":          false,
+		"a/":          false,
+		"a//b":        false,
+		"./a":         false,
+		"a/../b":      false,
+		"a\\b":        false,
+		"a\x00":       false,
+	} {
+		if got := ValidKey(key); got != want {
+			t.Errorf("ValidKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	logger := log.NewWithZap(zap.NewNop())
+
+	for driver, want := range map[string]string{
+		"":        "<nil>",
+		"memory":  "*storage.Memory",
+		"local":   "*storage.Local",
+		"s3":      "*storage.S3",
+		"unknown": "<nil>",
+	} {
+		s := New(config.NewMap(map[string]string{
+			"STORAGE_DRIVER":    driver,
+			"STORAGE_PATH":      t.TempDir(),
+			"STORAGE_S3_BUCKET": "bucket",
+		}), logger)
+
+		if got := fmt.Sprintf("%T", s); got != want {
+			t.Errorf("driver %q: expected %s, got %s", driver, want, got)
+		}
+	}
+
+	// The bucket is required
+	if s := New(config.NewMap(map[string]string{"STORAGE_DRIVER": "s3"}), logger); s != nil {
+		t.Errorf("expected no storage without a bucket, got %T", s)
+	}
+}
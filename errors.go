@@ -0,0 +1,170 @@
+package webber
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// ErrorHandler turns the error returned by a handler into a response.
+type ErrorHandler func(c *Context, err error)
+
+// HTTPError is an error with the response it should produce.
+type HTTPError struct {
+	// Status is the HTTP status code, 500 if zero
+	Status int
+	// Code is a machine readable error code, such as "user_not_found"
+	Code string
+	// Message is the human readable detail sent to the client
+	Message string
+	// Err is the underlying error, it is logged but not sent to the client
+	Err error
+}
+
+// NewHTTPError returns an HTTPError with the given status, code and message.
+func NewHTTPError(status int, code, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+func (e HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.status())
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e HTTPError) status() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Code is the Code of an HTTPError
+	Code string `json:"code,omitempty"`
+	// Errors lists the invalid fields of a validation error
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a field that failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// ProblemContentType is the content type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// SetErrorHandler replaces the ErrorHandler, DefaultErrorHandler by default.
+func (a *App) SetErrorHandler(h ErrorHandler) {
+	a.errorHandler = h
+}
+
+// DefaultErrorHandler responds with the Problem of the error, see NewProblem.
+// Server errors are logged with the request ID, their detail is not sent to the client.
+// Nothing is written if the handler already started the response.
+func DefaultErrorHandler(c *Context, err error) {
+	p := NewProblem(err)
+	p.Instance = c.Request.URL.Path
+
+	if p.Status >= http.StatusInternalServerError {
+		c.Logger.Errorf("Error handling %s %s (request %s): %v", c.Request.Method, c.Request.URL.Path, c.RequestID(), err)
+	}
+
+	if c.Writer.Written() {
+		return
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// NewProblem maps an error to a Problem:
+//
+//	HTTPError                  its Status, Code and Message
+//	gorm.ErrRecordNotFound     404
+//	redis.Nil                  404
+//	validator.ValidationErrors 422 with the invalid fields
+//	any other error            500
+func NewProblem(err error) Problem {
+	p := Problem{Type: "about:blank", Status: http.StatusInternalServerError}
+
+	httpErr, isHTTPErr := asHTTPError(err)
+
+	var validation validator.ValidationErrors
+
+	switch {
+	case isHTTPErr:
+		p.Status = httpErr.status()
+		p.Code = httpErr.Code
+		p.Detail = httpErr.Message
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, redis.Nil):
+		p.Status = http.StatusNotFound
+	case errors.As(err, &validation):
+		p.Status = http.StatusUnprocessableEntity
+		for _, fe := range validation {
+			// The namespace starts with the name of the validated struct
+			field := fe.Namespace()
+			if _, f, ok := strings.Cut(field, "."); ok {
+				field = f
+			}
+			p.Errors = append(p.Errors, FieldError{Field: field, Rule: fe.Tag(), Param: fe.Param()})
+		}
+	}
+
+	p.Title = http.StatusText(p.Status)
+
+	return p
+}
+
+// badRequest returns an HTTPError with status 400 and the given message.
+func badRequest(message string) error {
+	return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_request", Message: message}
+}
+
+// bindError returns the validation errors as is, and the errors that
+// prevented binding the request as a 400 HTTPError.
+func bindError(err error) error {
+	var validation validator.ValidationErrors
+	if errors.As(err, &validation) {
+		return err
+	}
+	return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_request", Message: err.Error(), Err: err}
+}
+
+// asHTTPError finds an HTTPError or a *HTTPError in the chain of err.
+func asHTTPError(err error) (HTTPError, bool) {
+	var ptr *HTTPError
+	if errors.As(err, &ptr) && ptr != nil {
+		return *ptr, true
+	}
+
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+
+	return HTTPError{}, false
+}
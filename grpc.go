@@ -0,0 +1,272 @@
+package webber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// grpcRequestIDKey is the metadata key of the request ID.
+	grpcRequestIDKey = "x-request-id"
+	// grpcServerName names the listener of the gRPC server passed by Upgrade.
+	grpcServerName = "gRPC"
+)
+
+type grpcServer struct {
+	app *App
+
+	// host and port are set when the server has its own port,
+	// it is multiplexed on the HTTP port otherwise
+	host string
+	port int
+
+	server   *grpc.Server
+	health   *grpchealth.Server
+	listener net.Listener
+	// err is the error of the TLS configuration
+	err error
+
+	// inflight tracks the calls served by the HTTP server,
+	// GracefulStop does not support them. closing is set under mu
+	// when the shutdown starts, the calls received after are rejected.
+	mu       sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
+}
+
+// RegisterGRPC registers services on the gRPC server. The server listens on
+// GRPC_HOST:GRPC_PORT if GRPC_PORT is set, otherwise the gRPC requests are
+// served on the HTTP port by content type. Both use TLS when CERT_FILE and
+// KEY_FILE are set. The standard health service reports the readiness of the App.
+func (a *App) RegisterGRPC(fn func(s *grpc.Server)) {
+	if a.grpc == nil {
+		a.grpc = newGRPCServer(a)
+	}
+
+	fn(a.grpc.server)
+}
+
+func newGRPCServer(a *App) *grpcServer {
+	s := &grpcServer{
+		app:    a,
+		host:   a.Config.GetString("GRPC_HOST", "localhost"),
+		health: grpchealth.NewServer(),
+	}
+	s.port, _ = a.Config.GetInt("GRPC_PORT", 0)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	}
+
+	// The server on its own port uses the TLS settings of the HTTP server,
+	// the multiplexed one is served over the TLS connections of the HTTP server
+	if h := a.httpServer; s.port != 0 && h.certFile != "" && h.keyFile != "" {
+		cfg, err := h.tlsConfig()
+		if err != nil {
+			// Reported by Listen, RegisterGRPC does not return errors
+			s.err = err
+		} else {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+		}
+	}
+
+	s.server = grpc.NewServer(opts...)
+
+	// The App is not serving until it is ready
+	s.health.Shutdown()
+	healthpb.RegisterHealthServer(s.server, s.health)
+
+	if s.port == 0 {
+		a.httpServer.grpc = s
+		a.httpServer.registered = true
+	}
+
+	return s
+}
+
+// GRPCContext returns the *Context of a gRPC call, it gives the handlers access
+// to the container with a logger scoped to the call.
+func GRPCContext(ctx context.Context) *Context {
+	if c, ok := ctx.Value(grpcContextKey{}).(*Context); ok {
+		return c
+	}
+	return &Context{ctx: ctx}
+}
+
+type grpcContextKey struct{}
+
+// call sets the request ID and the *Context of a call, then logs it once handled.
+func (s *grpcServer) call(ctx context.Context, method string, handle func(ctx context.Context) error) (err error) {
+	start := time.Now()
+
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(grpcRequestIDKey); len(v) > 0 && validRequestID(v[0]) {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = randomHex(16)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(grpcRequestIDKey, id))
+
+	ctx = log.WithRequestID(ctx, id)
+
+	container := *s.app.container
+	container.Logger = log.With(s.app.container.Logger, zap.String("request_id", id), zap.String("method", method))
+
+	c := &Context{Container: &container, app: s.app}
+	ctx = context.WithValue(ctx, grpcContextKey{}, c)
+	c.ctx = ctx
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.Logger.Errorf("Panic handling %s: %v\n%s", method, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+
+		code := status.Code(err)
+		switch code {
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			c.Logger.Errorf("%s %s %s: %v", method, code, time.Since(start), err)
+		default:
+			c.Logger.Infof("%s %s %s", method, code, time.Since(start))
+		}
+	}()
+
+	return handle(ctx)
+}
+
+func (s *grpcServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	err = s.call(ctx, info.FullMethod, func(ctx context.Context) error {
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *grpcServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.call(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &grpcStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// grpcStream replaces the context of a stream.
+type grpcStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcStream) Context() context.Context {
+	return s.ctx
+}
+
+// ServeHTTP serves a gRPC call multiplexed on the HTTP server.
+func (s *grpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+		w.Header().Set("Grpc-Message", "server is shutting down")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
+
+	s.server.ServeHTTP(w, r)
+}
+
+// isGRPCRequest reports whether a request received by the HTTP server is a gRPC call.
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+func (s *grpcServer) addr() string {
+	return fmt.Sprintf("%s:%d", s.host, s.port)
+}
+
+// Listen binds the port of the server if it has its own, or takes over the
+// listener passed by the parent process during an Upgrade.
+func (s *grpcServer) Listen() error {
+	if s.port == 0 {
+		return nil
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	ln, err := inheritedListener(grpcServerName)
+	if err != nil {
+		return err
+	}
+	if ln == nil {
+		if ln, err = net.Listen("tcp", s.addr()); err != nil {
+			return err
+		}
+	}
+	s.listener = ln
+
+	return nil
+}
+
+func (s *grpcServer) Run() error {
+	s.app.Logger().Infof("Starting gRPC server on %s", s.addr())
+
+	err := s.server.Serve(s.listener)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown waits for the calls in progress, the remaining ones are
+// canceled when ctx is done. The calls received after, on the HTTP/2
+// connections left open, are rejected as Unavailable.
+func (s *grpcServer) Shutdown(ctx context.Context) error {
+	if s.listener == nil {
+		// Multiplexed on the HTTP server which no longer accepts calls
+		s.mu.Lock()
+		s.closing = true
+		s.mu.Unlock()
+
+		return ShutdownWithContext(ctx, func(context.Context) error {
+			s.inflight.Wait()
+			s.server.Stop()
+			return nil
+		}, func() error {
+			s.server.Stop()
+			return nil
+		})
+	}
+
+	return ShutdownWithContext(ctx, func(context.Context) error {
+		s.server.GracefulStop()
+		return nil
+	}, func() error {
+		s.server.Stop()
+		return nil
+	})
+}
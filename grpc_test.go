@@ -0,0 +1,77 @@
+package webber_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber/webbertest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCShutdownRejectsCalls(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"HTTP_HOST": "127.0.0.1",
+		"HTTP_PORT": strconv.Itoa(port),
+	}))
+	app.RegisterGRPC(func(s *grpc.Server) {})
+
+	ready := make(chan struct{})
+	app.OnReady(func(context.Context) error {
+		close(ready)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- app.RunContext(ctx) }()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("the App stopped before being ready: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the App is not ready")
+	}
+
+	conn, err := grpc.NewClient("127.0.0.1:"+strconv.Itoa(port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected the call to succeed, got %v", err)
+	}
+
+	// The h2c connection outlives the shutdown of the HTTP server
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the App did not stop")
+	}
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected the call after the shutdown to be unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package webber
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// StatusCoder is implemented by responses of typed handlers that set
+// the response status code, 200 by default.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Handle registers a typed handler. Req is a struct bound from
+//
+//	path params    `uri:"id"`
+//	query          `form:"page"`
+//	headers        `header:"X-Tenant"`
+//	body           decoded by the codec of the content type, or `form:"name"`
+//
+// and validated with the `binding` tags. Only the fields carrying the tag of a
+// source are bound from the query, the headers and the path. Binding failures
+// respond with 400, unsupported content types with 415 and validation failures
+// with 422.
+// The returned Resp is sent with Respond, nothing is sent if the handler
+// already wrote the response.
+func Handle[Req, Resp any](r Router, method, path string, fn func(c *Context, req Req) (Resp, error)) {
+	r.handle(method, path, typedHandler(fn))
+}
+
+// Typed adapts a typed handler to a Handler, see Handle. Unlike with Handle,
+// the request and response types are not documented in the OpenAPI document.
+func Typed[Req, Resp any](fn func(c *Context, req Req) (Resp, error)) Handler {
+	return typedHandler(fn).fn
+}
+
+// Router is an App or a RouterGroup, on which Handle registers typed handlers.
+type Router interface {
+	handle(method, path string, h routeHandler)
+}
+
+func typedHandler[Req, Resp any](fn func(c *Context, req Req) (Resp, error)) routeHandler {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	sources := newBindSources(reqType)
+
+	h := func(c *Context) error {
+		var req Req
+		if err := bindRequest(c, &req, sources); err != nil {
+			return err
+		}
+
+		resp, err := fn(c, req)
+		if err != nil {
+			return err
+		}
+
+		if c.Writer.Written() {
+			return nil
+		}
+
+		status := http.StatusOK
+		if sc, ok := any(resp).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+
+		return c.Respond(status, resp)
+	}
+
+	return routeHandler{
+		fn:   h,
+		req:  reqType,
+		resp: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+}
+
+// bindSources are the names bound from the query, the headers and the path
+// params. Only the fields tagged with `form`, `header` or `uri` are bound from
+// those sources, gin would otherwise bind the other fields by their Go name.
+type bindSources struct {
+	query  []string
+	header []string
+	uri    []string
+}
+
+func newBindSources(t reflect.Type) bindSources {
+	var s bindSources
+	s.add(t, map[reflect.Type]bool{})
+	return s
+}
+
+// add collects the tags of the fields of t and of its nested structs, which gin binds too.
+func (s *bindSources) add(t reflect.Type, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		if name := tagName(f, "form"); name != "" {
+			s.query = append(s.query, name)
+		}
+		if name := tagName(f, "header"); name != "" {
+			s.header = append(s.header, name)
+		}
+		if name := tagName(f, "uri"); name != "" {
+			s.uri = append(s.uri, name)
+		}
+
+		s.add(f.Type, seen)
+	}
+}
+
+// pick returns the values of the given names.
+func pick(names []string, values func(name string) []string) map[string][]string {
+	m := make(map[string][]string, len(names))
+	for _, name := range names {
+		if v := values(name); len(v) > 0 {
+			m[name] = v
+		}
+	}
+	return m
+}
+
+// bindRequest binds the query, headers, body and path params into req, in that
+// order, then validates it once all the sources are bound.
+func bindRequest(c *Context, req any, sources bindSources) error {
+	bind := func(err error) error {
+		// Validation is done once all the fields are set
+		var validation validator.ValidationErrors
+		if err == nil || errors.As(err, &validation) {
+			return nil
+		}
+		return bindError(err)
+	}
+
+	if len(sources.query) > 0 {
+		query := c.Request.URL.Query()
+		if err := bind(binding.MapFormWithTag(req, pick(sources.query, func(name string) []string { return query[name] }), "form")); err != nil {
+			return err
+		}
+	}
+
+	if len(sources.header) > 0 {
+		if err := bind(binding.MapFormWithTag(req, pick(sources.header, c.Request.Header.Values), "header")); err != nil {
+			return err
+		}
+	}
+
+	if c.Request.Body != nil && c.Request.ContentLength != 0 && c.Request.Method != http.MethodGet {
+		if c.isForm() {
+			if err := bind(c.ShouldBindWith(req, binding.Default(c.Request.Method, c.ContentType()))); err != nil {
+				return err
+			}
+		} else if err := c.decodeBody(req); err != nil {
+			return err
+		}
+	}
+
+	if len(sources.uri) > 0 {
+		params := pick(sources.uri, func(name string) []string {
+			if v, ok := c.Params.Get(name); ok {
+				return []string{v}
+			}
+			return nil
+		})
+		if err := bind(binding.MapFormWithTag(req, params, "uri")); err != nil {
+			return err
+		}
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(req)
+}
@@ -0,0 +1,53 @@
+package webber_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+type bindRequest struct {
+	ID      int    `uri:"id" json:"id"`
+	Page    int    `form:"page,default=1" json:"page"`
+	Tenant  string `header:"X-Tenant" json:"tenant"`
+	Name    string `json:"name"`
+	IsAdmin bool   `json:"is_admin"`
+	Filter  struct {
+		Status string `form:"status" json:"status"`
+	} `json:"filter"`
+}
+
+func TestHandleBindSources(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	webber.Handle(app, http.MethodPost, "/users/:id", func(_ *webber.Context, req bindRequest) (bindRequest, error) {
+		return req, nil
+	})
+
+	var got bindRequest
+	app.Client().Post("/users/5").
+		WithQuery("IsAdmin", "true").
+		WithQuery("Name", "query").
+		WithQuery("status", "active").
+		WithHeader("IsAdmin", "true").
+		WithHeader("Is-Admin", "true").
+		WithHeader("X-Tenant", "acme").
+		WithJSON(map[string]interface{}{"name": "bob"}).
+		Do().Status(http.StatusOK).JSON(&got)
+
+	if got.IsAdmin || got.Name != "bob" {
+		t.Errorf("expected the untagged fields to be bound from the body only, got %+v", got)
+	}
+	if got.ID != 5 || got.Page != 1 || got.Tenant != "acme" || got.Filter.Status != "active" {
+		t.Errorf("expected the tagged fields to be bound, got %+v", got)
+	}
+
+	app.Client().Post("/users/5").WithQuery("page", "2").WithJSON(map[string]interface{}{"is_admin": true}).
+		Do().Status(http.StatusOK).JSON(&got)
+
+	if !got.IsAdmin || got.Page != 2 {
+		t.Errorf("expected the body and the query to be bound, got %+v", got)
+	}
+}
@@ -1,3 +1,109 @@
 package webber
 
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerFunc is a route handler writing its response itself, registered
+// with Get, Post, Put, Patch, Delete and Any.
 type HandlerFunc func(c *Context)
+
+// Handler is a route handler returning an error, registered with GetE, PostE,
+// PutE, PatchE, DeleteE and AnyE. The error is turned into a response by the
+// ErrorHandler. A typed handler is adapted with Typed or registered with Handle.
+type Handler func(c *Context) error
+
+// handler adapts the HandlerFunc to a Handler.
+func (h HandlerFunc) handler() Handler {
+	return func(c *Context) error {
+		h(c)
+		return nil
+	}
+}
+
+// routeHandler is a route Handler with the request and response types of
+// a typed handler, they are kept for the OpenAPI document.
+type routeHandler struct {
+	fn   Handler
+	req  reflect.Type
+	resp reflect.Type
+}
+
+// ginHandler adapts a Handler to a gin.HandlerFunc.
+func (a *App) ginHandler(fn Handler) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c := a.context(ctx)
+
+		err := fn(c)
+
+		// Nothing is written to the event stream once the handler returns
+		if c.stream != nil {
+			c.stream.close()
+		}
+
+		if err == nil {
+			return
+		}
+		if c.stream != nil {
+			// The status of the event stream is already sent
+			c.Logger.Errorf("Error handling event stream %s (request %s): %v", c.Request.URL.Path, c.RequestID(), err)
+			return
+		}
+		a.errorHandler(c, err)
+	}
+}
+
+// MiddlewareFunc is a middleware receiving the *Context of the request,
+// it calls c.Next to run the next handlers or c.Abort to stop the chain.
+type MiddlewareFunc func(c *Context)
+
+// Middleware is the middleware of App.UseMiddleware, RouterGroup.Use and
+// RouterGroup.Group. App.Use and App.Group take gin middleware.
+type Middleware = MiddlewareFunc
+
+// ginMiddleware adapts middleware to gin handlers.
+func (a *App) ginMiddleware(middleware []Middleware) []gin.HandlerFunc {
+	handlers := make([]gin.HandlerFunc, 0, len(middleware))
+
+	for _, m := range middleware {
+		handlers = append(handlers, func(ctx *gin.Context) {
+			m(a.context(ctx))
+		})
+	}
+
+	return handlers
+}
+
+// contextKey stores the *Context of a request in the gin keys.
+const contextKey = "webber.context"
+
+// context returns the *Context of the request, it is created by the
+// first middleware or handler and shared by the following ones.
+func (a *App) context(ctx *gin.Context) *Context {
+	if v, ok := ctx.Get(contextKey); ok {
+		if c, ok := v.(*Context); ok && c.Context == ctx {
+			return c
+		}
+	}
+
+	// The container is copied so that the logger and the database
+	// session are scoped to the request
+	container := *a.container
+	container.Logger = requestLogger(ctx, a.container.Logger)
+	if a.container.DB != nil && a.container.DB.DB != nil {
+		db := *a.container.DB
+		db.DB = db.WithContext(ctx.Request.Context())
+		container.DB = &db
+	}
+
+	c := &Context{
+		Container: &container,
+		Context:   ctx,
+		app:       a,
+	}
+	ctx.Set(contextKey, c)
+
+	return c
+}
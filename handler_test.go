@@ -0,0 +1,130 @@
+package webber_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/log"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+type greetRequest struct {
+	Name string `uri:"name" binding:"required"`
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(_ *webber.Context, req greetRequest) (greetResponse, error) {
+	return greetResponse{Greeting: "hello " + req.Name}, nil
+}
+
+func TestHandlerForms(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	// The gin middleware of Use and Group are kept as they are
+	app.Use(func(c *gin.Context) {
+		c.Header("X-Gin", "1")
+		c.Next()
+	})
+	app.UseMiddleware(func(c *webber.Context) {
+		c.Header("X-Webber", c.RequestID())
+		c.Next()
+	})
+
+	api := app.Group("/api", func(c *gin.Context) {
+		c.Header("X-Gin-Group", "1")
+		c.Next()
+	})
+	api.Use(func(c *webber.Context) {
+		c.Header("X-Group", c.FullPath())
+		c.Next()
+	})
+
+	api.Get("/func", func(c *webber.Context) {
+		c.String(http.StatusOK, "func")
+	})
+	api.GetE("/error", func(c *webber.Context) error {
+		return webber.NewHTTPError(http.StatusConflict, "conflict", "already exists")
+	})
+	api.GetE("/typed/:name", webber.Typed(greet))
+	webber.Handle(api, http.MethodGet, "/handle/:name", greet)
+
+	client := app.Client()
+	client.Get("/api/func").WithHeader("X-Request-ID", "req-1").Do().
+		Status(http.StatusOK).
+		Header("X-Gin", "1").
+		Header("X-Webber", "req-1").
+		Header("X-Gin-Group", "1").
+		Header("X-Group", "/api/func").
+		BodyContains("func")
+	client.Get("/api/error").Do().
+		Status(http.StatusConflict).
+		Header("Content-Type", "application/problem+json")
+	client.Get("/api/typed/ann").Do().Status(http.StatusOK).JSONEq(greetResponse{Greeting: "hello ann"})
+	client.Get("/api/handle/bob").Do().Status(http.StatusOK).JSONEq(greetResponse{Greeting: "hello bob"})
+
+	paths := app.OpenAPI()["paths"].(map[string]interface{})
+	for path, typed := range map[string]bool{"/api/typed/{name}": false, "/api/handle/{name}": true} {
+		op := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		_, documented := op["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"]
+		if documented != typed {
+			t.Errorf("%s: expected the response to be documented %v, got %v", path, typed, documented)
+		}
+	}
+}
+
+func TestHandlerErrorAfterWrite(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.GetE("/", func(c *webber.Context) error {
+		c.String(http.StatusAccepted, "written")
+		return errors.New("failed after writing")
+	})
+
+	app.Client().Get("/").Do().Status(http.StatusAccepted).BodyContains("written")
+}
+
+func TestHandlerErrorAfterSSE(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+	app.SetErrorHandler(func(c *webber.Context, err error) {
+		c.String(http.StatusInternalServerError, "problem")
+	})
+
+	app.GetE("/events", func(c *webber.Context) error {
+		if err := c.SSE().Send(webber.SSEEvent{Data: "first"}); err != nil {
+			return err
+		}
+		return errors.New("failed after streaming")
+	})
+
+	res := app.Client().Get("/events").Do().
+		Status(http.StatusOK).
+		Header("Content-Type", "text/event-stream").
+		BodyContains("data: first")
+
+	if strings.Contains(res.Body.String(), "problem") {
+		t.Errorf("expected no problem in the event stream, got %q", res.Body.String())
+	}
+	if app.Logs().FilterMessageSnippet("failed after streaming").Len() != 1 {
+		t.Errorf("expected the error to be logged")
+	}
+}
+
+func TestHandlerDBContext(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutRedis())
+
+	app.GetE("/", func(c *webber.Context) error {
+		if id := log.RequestID(c.DB.Statement.Context); id != c.RequestID() {
+			t.Errorf("expected the database session to carry the request ID %q, got %q", c.RequestID(), id)
+		}
+		return nil
+	})
+
+	app.Client().Get("/").WithHeader("X-Request-ID", "req-1").Do().Status(http.StatusOK)
+}
@@ -0,0 +1,250 @@
+package webber
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultHealthCheckCacheTTL = 2 * time.Second
+)
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// HealthCheckFunc reports whether a component is healthy.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheckOption configures a health check.
+type HealthCheckOption func(*healthCheck)
+
+// WithHealthCheckTimeout sets the maximum duration of the check.
+func WithHealthCheckTimeout(timeout time.Duration) HealthCheckOption {
+	return func(h *healthCheck) {
+		h.timeout = timeout
+	}
+}
+
+// WithHealthCheckCacheTTL sets how long the result of the check is reused.
+func WithHealthCheckCacheTTL(ttl time.Duration) HealthCheckOption {
+	return func(h *healthCheck) {
+		h.cacheTTL = ttl
+	}
+}
+
+// HealthStatus is the result of a health check.
+type HealthStatus struct {
+	Status      string     `json:"status"`
+	Duration    string     `json:"duration"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// HealthReport is the body of the readiness endpoint.
+type HealthReport struct {
+	Status     string                  `json:"status"`
+	Components map[string]HealthStatus `json:"components,omitempty"`
+}
+
+type healthCheck struct {
+	name     string
+	fn       HealthCheckFunc
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	status HealthStatus
+}
+
+type health struct {
+	checks []*healthCheck
+	ready  atomic.Bool
+
+	// livenessPath is the path of the liveness endpoint, empty if it is disabled
+	livenessPath  string
+	readinessPath string
+	served        sync.Once
+}
+
+// AddHealthCheck registers a check reported by the readiness endpoint.
+func (a *App) AddHealthCheck(name string, fn HealthCheckFunc, opts ...HealthCheckOption) {
+	h := &healthCheck{
+		name:     name,
+		fn:       fn,
+		timeout:  defaultHealthCheckTimeout,
+		cacheTTL: defaultHealthCheckCacheTTL,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	a.health.checks = append(a.health.checks, h)
+}
+
+// initHealth registers the database and redis checks and reads the paths of the
+// liveness (HEALTH_LIVENESS_PATH, default /healthz) and readiness
+// (HEALTH_READINESS_PATH, default /readyz) endpoints. An empty path disables
+// the endpoint.
+func (a *App) initHealth() {
+	if a.container.DB != nil {
+		a.AddHealthCheck("db", a.container.DB.Ping)
+	}
+
+	if a.container.Redis != nil {
+		a.AddHealthCheck("redis", func(ctx context.Context) error {
+			return a.container.Redis.Ping(ctx).Err()
+		})
+	}
+
+	a.health.livenessPath = a.Config.GetString("HEALTH_LIVENESS_PATH", "/healthz")
+	a.health.readinessPath = a.Config.GetString("HEALTH_READINESS_PATH", "/readyz")
+}
+
+// serveHealth serves the health endpoints on the admin listener if configured,
+// or on the HTTP server. They are registered once the routes of the App are
+// known, a path the App already routes is left to its handler. The errors of
+// the checks are only reported on the admin listener.
+func (a *App) serveHealth() {
+	a.health.served.Do(func() {
+		r, public := a.httpServer.router, true
+		if a.adminServer != nil {
+			r, public = a.adminServer.router, false
+		}
+
+		routed := map[string]bool{}
+		for _, route := range r.Routes() {
+			if route.Method == http.MethodGet {
+				routed[route.Path] = true
+			}
+		}
+
+		serve := func(path string, h gin.HandlerFunc) {
+			switch {
+			case path == "":
+			case routed[path]:
+				a.Logger().Warnf("Health endpoint %s is served by the App", path)
+			default:
+				r.GET(path, h)
+			}
+		}
+
+		// Liveness only reports that the process is serving requests
+		serve(a.health.livenessPath, func(c *gin.Context) {
+			c.JSON(http.StatusOK, HealthReport{Status: HealthUp})
+		})
+
+		serve(a.health.readinessPath, func(c *gin.Context) {
+			report := a.Readiness(c.Request.Context())
+			if public {
+				for name, status := range report.Components {
+					status.LastError = ""
+					report.Components[name] = status
+				}
+			}
+
+			status := http.StatusOK
+			if report.Status != HealthUp {
+				status = http.StatusServiceUnavailable
+			}
+
+			c.JSON(status, report)
+		})
+	})
+}
+
+// Readiness runs the health checks and reports whether the App can serve requests.
+// The App is not ready before Run has started it and once Shutdown has begun.
+func (a *App) Readiness(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:     HealthUp,
+		Components: make(map[string]HealthStatus, len(a.health.checks)),
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for _, h := range a.health.checks {
+		wg.Add(1)
+
+		go func(h *healthCheck) {
+			defer wg.Done()
+
+			status := h.check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Components[h.name] = status
+			if status.Status != HealthUp {
+				report.Status = HealthDown
+			}
+		}(h)
+	}
+
+	wg.Wait()
+
+	if !a.health.ready.Load() {
+		report.Status = HealthDown
+	}
+
+	return report
+}
+
+// check runs the check unless its cached result is still fresh.
+func (h *healthCheck) check(ctx context.Context) HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.status.CheckedAt.IsZero() && time.Since(h.status.CheckedAt) < h.cacheTTL {
+		return h.status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errCh <- h.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	h.status.Status = HealthUp
+	h.status.Duration = time.Since(start).String()
+	h.status.CheckedAt = time.Now()
+
+	if err != nil {
+		h.status.Status = HealthDown
+		h.status.LastError = err.Error()
+		checkedAt := h.status.CheckedAt
+		h.status.LastErrorAt = &checkedAt
+	}
+
+	return h.status
+}
@@ -0,0 +1,37 @@
+package webber_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+func TestHealthRoutedPath(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	// The route of the App is kept, the health endpoint is not registered over it
+	app.Get("/healthz", func(c *webber.Context) {
+		c.String(http.StatusOK, "mine")
+	})
+
+	app.Client().Get("/healthz").Do().Status(http.StatusOK).BodyContains("mine")
+	app.Client().Get("/readyz").Do().Status(http.StatusServiceUnavailable)
+}
+
+func TestHealthPublicReport(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.AddHealthCheck("db", func(context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	})
+
+	res := app.Client().Get("/readyz").Do().Status(http.StatusServiceUnavailable).BodyContains(`"db":{"status":"down"`)
+	if body := res.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "last_error\"") {
+		t.Errorf("expected the error to be left out of the public report, got %s", body)
+	}
+}
@@ -2,82 +2,200 @@ package webber
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/gin-contrib/pprof"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	"github.com/xbmlz/webber/container"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type httpServer struct {
+	name     string
 	host     string
 	port     int
 	certFile string
 	keyFile  string
+	tls      tlsSettings
 
-	router *gin.Engine
-	srv    *http.Server
+	// listen overrides host and port, see listen
+	listen     string
+	socketMode os.FileMode
+
+	logger   log.Logger
+	router   *gin.Engine
+	srv      *http.Server
+	listener net.Listener
+
+	// grpc serves the gRPC requests when multiplexed on the server
+	grpc http.Handler
+
+	// registered reports whether any route has been added,
+	// the server is only started if it has
+	registered bool
+
+	// err is the error of the configuration, reported by Listen
+	err error
 }
 
-func newHTTPServer(c *container.Container, host string, port int, mode string) *httpServer {
+func newHTTPServer(c *container.Container, name, host string, port int, mode string) *httpServer {
 
-	if mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	switch mode {
+	case gin.ReleaseMode, gin.TestMode:
+		gin.SetMode(mode)
 	}
 
 	r := gin.New()
 
-	pprof.Register(r)
-
 	// TODO: Add default middleware
 	r.Use(
-		ginzap.Ginzap(c.Logger.GetLogger(), time.DateTime, true),
+		requestIDMiddleware,
+		ginzap.GinzapWithConfig(c.Logger.GetLogger(), &ginzap.Config{
+			TimeFormat:   time.DateTime,
+			UTC:          true,
+			Context:      accessLogFields,
+			DefaultLevel: zapcore.InfoLevel,
+		}),
 		ginzap.RecoveryWithZap(c.Logger.GetLogger(), true),
 	)
 
 	return &httpServer{
+		name:   name,
 		host:   host,
 		port:   port,
+		logger: c.Logger,
 		router: r,
 	}
 }
 
-func (s *httpServer) Run(c *container.Container) {
+// Listen binds the server address so that requests can be accepted
+// as soon as Run is called.
+func (s *httpServer) Listen() error {
 	if s.srv != nil {
-		c.Logger.Warnf("Server already running on %s:%d", s.host, s.port)
+		return fmt.Errorf("%s server already running on %s", s.name, s.addr())
+	}
+	if s.err != nil {
+		return s.err
 	}
 
-	c.Logger.Infof("Starting server on %s:%d", s.host, s.port)
+	var tlsConfig *tls.Config
+	if s.certFile != "" && s.keyFile != "" {
+		cfg, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		tlsConfig = cfg
+	}
 
 	s.srv = &http.Server{
 		Addr:              fmt.Sprintf("%s:%d", s.host, s.port),
-		Handler:           s.router,
+		Handler:           s.handler(tlsConfig != nil),
+		TLSConfig:         tlsConfig,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	if s.certFile != "" && s.keyFile != "" {
-		// check file is exists
-		if _, err := os.Stat(s.certFile); os.IsNotExist(err) {
-			c.Logger.Errorf("Error loading %s: %v", s.certFile, err)
-		}
-		if _, err := os.Stat(s.keyFile); os.IsNotExist(err) {
-			c.Logger.Errorf("Error loading %s: %v", s.keyFile, err)
+	// The listener may have been handed over with WithListener
+	if s.listener != nil {
+		return nil
+	}
+
+	// Reuse the socket of the previous process during an Upgrade
+	ln, err := inheritedListener(s.name)
+	if err != nil {
+		return err
+	}
+
+	if ln == nil {
+		if ln, err = s.listenAddr(); err != nil {
+			return err
 		}
+	}
 
-		if err := s.srv.ListenAndServeTLS(s.certFile, s.keyFile); err != nil {
-			c.Logger.Errorf("Error starting server: %v", err)
+	s.listener = ln
+
+	return nil
+}
+
+// listenAddr creates the listener described by listen:
+//
+//	unix:///run/app.sock  a unix socket with socketMode, stale socket files are removed
+//	systemd               the first socket passed by systemd socket activation
+//	systemd:<name>        the socket named <name> in LISTEN_FDNAMES
+//
+// and a TCP listener on host:port if listen is empty.
+func (s *httpServer) listenAddr() (net.Listener, error) {
+	switch {
+	case s.listen == "":
+		return net.Listen("tcp", s.srv.Addr)
+	case strings.HasPrefix(s.listen, "unix://"):
+		return listenUnix(strings.TrimPrefix(s.listen, "unix://"), s.socketMode)
+	case s.listen == "systemd":
+		return systemdListener("")
+	case strings.HasPrefix(s.listen, "systemd:"):
+		return systemdListener(strings.TrimPrefix(s.listen, "systemd:"))
+	default:
+		return nil, fmt.Errorf("unsupported listen address %q", s.listen)
+	}
+}
+
+// addr returns the address the server listens on, for logging.
+func (s *httpServer) addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().Network() + "://" + s.listener.Addr().String()
+	}
+	return fmt.Sprintf("%s:%d", s.host, s.port)
+}
+
+// handler returns the router, preceded by the gRPC server when it is multiplexed.
+// Without TLS, HTTP/2 is served in cleartext for the gRPC clients.
+func (s *httpServer) handler(tls bool) http.Handler {
+	if s.grpc == nil {
+		return s.router
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			s.grpc.ServeHTTP(w, r)
+			return
 		}
-		return
+		s.router.ServeHTTP(w, r)
+	})
+
+	if tls {
+		return h
 	}
 
-	// If no certFile/keyFile is provided, run the HTTP server
-	if err := s.srv.ListenAndServe(); err != http.ErrServerClosed {
-		c.Logger.Errorf("Error starting server: %v", err)
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
+// Run serves requests on the bound listener until the server is shut down.
+func (s *httpServer) Run(c *container.Container) error {
+	c.Logger.Infof("Starting %s server on %s", s.name, s.addr())
+
+	var err error
+	if s.srv.TLSConfig != nil {
+		// The certificate is served by TLSConfig.GetCertificate
+		err = s.srv.ServeTLS(s.listener, "", "")
+	} else {
+		// If no certFile/keyFile is provided, run the HTTP server
+		err = s.srv.Serve(s.listener)
 	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
 }
 
 func (s *httpServer) Shutdown(ctx context.Context) error {
@@ -0,0 +1,117 @@
+package webber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultHookTimeout = 15 * time.Second
+
+// HookFunc is a function executed at a given stage of the App lifecycle.
+type HookFunc func(ctx context.Context) error
+
+// HookOption configures a lifecycle hook.
+type HookOption func(*hook)
+
+// WithHookName sets the name used to identify the hook in logs and errors.
+func WithHookName(name string) HookOption {
+	return func(h *hook) {
+		h.name = name
+	}
+}
+
+// WithHookTimeout sets the maximum duration the hook is allowed to run.
+func WithHookTimeout(timeout time.Duration) HookOption {
+	return func(h *hook) {
+		h.timeout = timeout
+	}
+}
+
+type hook struct {
+	name    string
+	fn      HookFunc
+	timeout time.Duration
+}
+
+type lifecycle struct {
+	start []hook
+	ready []hook
+	stop  []hook
+}
+
+func newHook(stage string, index int, fn HookFunc, opts ...HookOption) hook {
+	h := hook{
+		name:    fmt.Sprintf("%s hook #%d", stage, index+1),
+		fn:      fn,
+		timeout: defaultHookTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	return h
+}
+
+// OnStart registers a hook that runs before the servers are started.
+// Start hooks run in registration order; the first failing hook aborts Run.
+func (a *App) OnStart(fn HookFunc, opts ...HookOption) {
+	a.lifecycle.start = append(a.lifecycle.start, newHook("start", len(a.lifecycle.start), fn, opts...))
+}
+
+// OnReady registers a hook that runs once the servers are accepting requests.
+// Ready hooks run in registration order; a failing hook shuts the App down.
+func (a *App) OnReady(fn HookFunc, opts ...HookOption) {
+	a.lifecycle.ready = append(a.lifecycle.ready, newHook("ready", len(a.lifecycle.ready), fn, opts...))
+}
+
+// OnStop registers a hook that runs during Shutdown, after the servers are stopped.
+// Stop hooks run in reverse registration order and all of them are executed
+// even if some fail.
+func (a *App) OnStop(fn HookFunc, opts ...HookOption) {
+	a.lifecycle.stop = append(a.lifecycle.stop, newHook("stop", len(a.lifecycle.stop), fn, opts...))
+}
+
+// runHooks runs the given hooks in order and returns the first error.
+func (a *App) runHooks(ctx context.Context, hooks []hook) error {
+	for _, h := range hooks {
+		if err := a.runHook(ctx, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHooksReverse runs the given hooks in reverse order and joins all errors.
+func (a *App) runHooksReverse(ctx context.Context, hooks []hook) error {
+	var err error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		err = errors.Join(err, a.runHook(ctx, hooks[i]))
+	}
+	return err
+}
+
+func (a *App) runHook(ctx context.Context, h hook) error {
+	hookCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	a.Logger().Debugf("Running %s", h.name)
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- h.fn(hookCtx)
+	}()
+
+	select {
+	case <-hookCtx.Done():
+		return fmt.Errorf("%s: %w", h.name, hookCtx.Err())
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("%s: %w", h.name, err)
+		}
+		return nil
+	}
+}
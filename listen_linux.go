@@ -0,0 +1,116 @@
+//go:build linux
+
+package webber
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation.
+const listenFdsStart = 3
+
+// listenUnix listens on a unix socket at path with the given file mode.
+// A stale socket file left by a previous process is removed first,
+// the file is removed again when the listener is closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+
+		// Only remove the socket if nothing is listening on it anymore
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is already in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// systemdListener returns a listener passed by systemd socket activation
+// (LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES). The first socket is used
+// if name is empty.
+func systemdListener(name string) (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd: LISTEN_PID is not set to the current process")
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("no sockets passed by systemd: LISTEN_FDS is not set")
+	}
+
+	index := 0
+	if name != "" {
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+		index = -1
+		for i, fdName := range names {
+			if fdName == name && i < n {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("no socket named %q passed by systemd", name)
+		}
+	}
+
+	fd := listenFdsStart + index
+	syscall.CloseOnExec(fd)
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("systemd-socket-%d", fd))
+	defer f.Close()
+
+	// FileListener duplicates the file descriptor
+	return net.FileListener(f)
+}
+
+// inheritedListener returns the listener of the named server passed by the
+// parent process during an Upgrade, or nil if there is none.
+func inheritedListener(name string) (net.Listener, error) {
+	names := os.Getenv(envUpgradeFds)
+	if names == "" {
+		return nil, nil
+	}
+
+	for i, fdName := range strings.Split(names, ":") {
+		if fdName != name {
+			continue
+		}
+
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("inherited-socket-%d", fd))
+		defer f.Close()
+
+		// The socket file is only removed on close once this process
+		// is ready, see notifyUpgradeReady
+		return net.FileListener(f)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package webber
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+var errListenUnsupported = errors.New("unix sockets and systemd socket activation are only supported on linux")
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	return nil, errListenUnsupported
+}
+
+func systemdListener(name string) (net.Listener, error) {
+	return nil, errListenUnsupported
+}
+
+func inheritedListener(name string) (net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,20 @@
+package log
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID, the database
+// and redis queries made with it are logged with the ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, empty if there is none.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -54,9 +54,15 @@ type Config struct {
 	Compress   bool   // env var: LOG_COMPRESS
 }
 
+// LevelSetter is implemented by loggers whose level can be changed at runtime.
+type LevelSetter interface {
+	SetLevel(level string)
+}
+
 type logger struct {
 	config *Config
 	logger *zap.Logger
+	level  *zap.AtomicLevel
 }
 
 func New(level string) Logger {
@@ -69,16 +75,52 @@ func NewWithConfg(cfg config.Config) Logger {
 	logger := &logger{}
 	logger.loadConfig(cfg)
 	logger.initZapLogger(logger.config.Level, logger.config.Encoder)
+
+	config.OnChange(cfg, []string{"LOG_LEVEL"}, func(_, new map[string]string) {
+		level := defaultLevel
+		if v, ok := new["LOG_LEVEL"]; ok {
+			level = v
+		}
+		logger.SetLevel(level)
+		logger.Infof("Log level set to %s", level)
+	})
+
 	return logger
 }
 
+// NewWithZap returns a Logger writing to the given zap logger.
+func NewWithZap(zl *zap.Logger) Logger {
+	return &logger{logger: zl}
+}
+
+// With returns a Logger adding the fields to each entry logged with l,
+// it follows the level of l.
+func With(l Logger, fields ...zap.Field) Logger {
+	zl := l.GetLogger()
+	if zl == nil {
+		return l
+	}
+	return &logger{logger: zl.With(fields...)}
+}
+
 func (l *logger) GetLogger() *zap.Logger {
 	return l.logger
 }
 
+// SetLevel changes the level of the logger, it has no effect
+// on loggers created with NewWithZap.
+func (l *logger) SetLevel(level string) {
+	if l.level != nil {
+		l.level.SetLevel(ParseLevel(level))
+	}
+}
+
 func (l *logger) initZapLogger(level, encoder string) {
+	atomicLevel := zap.NewAtomicLevelAt(ParseLevel(level))
+	l.level = &atomicLevel
+
 	cores := []zapcore.Core{
-		l.getConsoleCore(level, encoder),
+		l.getConsoleCore(encoder),
 	}
 	if l.config != nil && l.config.File != "" {
 		// create file path if not exists
@@ -102,7 +144,7 @@ func (l *logger) initZapLogger(level, encoder string) {
 	l.logger = logger
 }
 
-func (l *logger) getConsoleCore(level, encoder string) zapcore.Core {
+func (l *logger) getConsoleCore(encoder string) zapcore.Core {
 	consoleEncoderConfig := zap.NewProductionEncoderConfig()
 	consoleEncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	consoleEncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.DateTime)
@@ -120,7 +162,7 @@ func (l *logger) getConsoleCore(level, encoder string) zapcore.Core {
 	return zapcore.NewCore(
 		consoleEncoder,
 		zapcore.AddSync(colorable.NewColorableStdout()),
-		zap.NewAtomicLevelAt(ParseLevel(level)),
+		l.level,
 	)
 }
 
@@ -149,7 +191,7 @@ func (l *logger) getFileCore() zapcore.Core {
 	return zapcore.NewCore(
 		fileEncoder,
 		zapcore.AddSync(hook),
-		zap.NewAtomicLevelAt(ParseLevel(l.config.Level)),
+		l.level,
 	)
 }
 
@@ -0,0 +1,223 @@
+package webber
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Module is a feature package that contributes routes, models, seeds,
+// cron jobs, middleware and lifecycle hooks to an App.
+// Besides Name, a module implements any of the optional Module* interfaces.
+type Module interface {
+	// Name uniquely identifies the module, it is used for dependencies,
+	// MODULES_DISABLED and as the default route prefix.
+	Name() string
+}
+
+// ModuleDependencies is implemented by modules that must be registered after other modules.
+type ModuleDependencies interface {
+	DependsOn() []string
+}
+
+// ModuleRoutes is implemented by modules that serve HTTP routes.
+// The routes are registered on a group prefixed with "/" + Name,
+// unless the module implements ModulePrefix.
+type ModuleRoutes interface {
+	Routes(g *RouterGroup)
+}
+
+// ModulePrefix is implemented by modules that serve their routes on a custom prefix.
+type ModulePrefix interface {
+	Prefix() string
+}
+
+// ModuleMiddleware is implemented by modules that apply middleware to their routes.
+type ModuleMiddleware interface {
+	Middleware() []Middleware
+}
+
+// ModuleModels is implemented by modules that migrate GORM models.
+type ModuleModels interface {
+	Models() []interface{}
+}
+
+// ModuleSeeds is implemented by modules that seed the database.
+type ModuleSeeds interface {
+	Seeds() []interface{}
+}
+
+// ModuleCronJobs is implemented by modules that schedule cron jobs.
+type ModuleCronJobs interface {
+	CronJobs() []CronJob
+}
+
+// ModuleStarter is implemented by modules that run code when the App starts.
+type ModuleStarter interface {
+	Start(ctx context.Context) error
+}
+
+// ModuleStopper is implemented by modules that run code when the App stops.
+type ModuleStopper interface {
+	Stop(ctx context.Context) error
+}
+
+// CronJob is a job scheduled by a module, Name defaults to the spec.
+type CronJob struct {
+	Name string
+	Spec string
+	Func CronFunc
+}
+
+// Register wires the given modules into the App in dependency order.
+// Modules listed in MODULES_DISABLED (comma separated) are skipped, and it is
+// an error for an enabled module to depend on a disabled or unknown module.
+func (a *App) Register(modules ...Module) error {
+	disabled := map[string]bool{}
+	for _, name := range strings.Split(a.Config.GetString("MODULES_DISABLED", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+
+	pending := map[string]Module{}
+	for _, m := range modules {
+		if _, ok := pending[m.Name()]; ok {
+			return fmt.Errorf("module %q registered more than once", m.Name())
+		}
+		if _, ok := a.modules[m.Name()]; ok {
+			return fmt.Errorf("module %q registered more than once", m.Name())
+		}
+		pending[m.Name()] = m
+	}
+
+	sorted, err := a.sortModules(modules, pending, disabled)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sorted {
+		if err := a.registerModule(m); err != nil {
+			return fmt.Errorf("module %q: %w", m.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// sortModules orders the enabled modules so that every module comes after its dependencies,
+// keeping the given order otherwise.
+func (a *App) sortModules(modules []Module, pending map[string]Module, disabled map[string]bool) ([]Module, error) {
+	var (
+		sorted   []Module
+		visited  = map[string]bool{}
+		visiting = map[string]bool{}
+		visit    func(m Module) error
+	)
+
+	visit = func(m Module) error {
+		name := m.Name()
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("module %q has a circular dependency", name)
+		}
+		visiting[name] = true
+
+		if d, ok := m.(ModuleDependencies); ok {
+			for _, dep := range d.DependsOn() {
+				if disabled[dep] {
+					return fmt.Errorf("module %q depends on disabled module %q", name, dep)
+				}
+				if _, ok := a.modules[dep]; ok {
+					continue
+				}
+				depModule, ok := pending[dep]
+				if !ok {
+					return fmt.Errorf("module %q depends on unknown module %q", name, dep)
+				}
+				if err := visit(depModule); err != nil {
+					return err
+				}
+			}
+		}
+
+		visiting[name] = false
+		visited[name] = true
+		sorted = append(sorted, m)
+
+		return nil
+	}
+
+	for _, m := range modules {
+		if disabled[m.Name()] {
+			a.Logger().Infof("Module %s is disabled", m.Name())
+			continue
+		}
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+func (a *App) registerModule(m Module) error {
+	if a.modules == nil {
+		a.modules = map[string]Module{}
+	}
+	a.modules[m.Name()] = m
+
+	if r, ok := m.(ModuleRoutes); ok {
+		prefix := "/" + m.Name()
+		if p, ok := m.(ModulePrefix); ok {
+			prefix = p.Prefix()
+		}
+
+		var middleware []Middleware
+		if mw, ok := m.(ModuleMiddleware); ok {
+			middleware = mw.Middleware()
+		}
+
+		g := a.Group(prefix)
+		g.Use(middleware...)
+		r.Routes(g)
+	}
+
+	if mm, ok := m.(ModuleModels); ok {
+		if a.container.DB == nil {
+			return fmt.Errorf("migrate: %w", errDBNotConfigured)
+		}
+		a.AddModels(mm.Models()...)
+	}
+
+	if ms, ok := m.(ModuleSeeds); ok {
+		if a.container.DB == nil {
+			return fmt.Errorf("seed: %w", errDBNotConfigured)
+		}
+		a.AddSeeds(ms.Seeds()...)
+	}
+
+	if mc, ok := m.(ModuleCronJobs); ok {
+		for _, job := range mc.CronJobs() {
+			var opts []CronOption
+			if job.Name != "" {
+				opts = append(opts, WithCronName(job.Name))
+			}
+			a.AddCronJob(job.Spec, job.Func, opts...)
+		}
+	}
+
+	if ms, ok := m.(ModuleStarter); ok {
+		a.OnStart(ms.Start, WithHookName(fmt.Sprintf("module %s start hook", m.Name())))
+	}
+
+	if ms, ok := m.(ModuleStopper); ok {
+		a.OnStop(ms.Stop, WithHookName(fmt.Sprintf("module %s stop hook", m.Name())))
+	}
+
+	a.Logger().Debugf("Registered module %s", m.Name())
+
+	return nil
+}
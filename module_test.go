@@ -0,0 +1,42 @@
+package webber_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+type greeterModule struct{}
+
+func (greeterModule) Name() string { return "greeter" }
+
+func (greeterModule) Middleware() []webber.Middleware {
+	return []webber.Middleware{
+		func(c *webber.Context) {
+			c.Header("X-Module", c.FullPath())
+			c.Next()
+		},
+	}
+}
+
+func (greeterModule) Routes(g *webber.RouterGroup) {
+	g.GetE("/hello", func(c *webber.Context) error {
+		c.String(http.StatusOK, "hello")
+		return nil
+	})
+}
+
+func TestModuleMiddleware(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	if err := app.Register(greeterModule{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Client().Get("/greeter/hello").Do().
+		Status(http.StatusOK).
+		Header("X-Module", "/greeter/hello").
+		BodyContains("hello")
+}
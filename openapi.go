@@ -0,0 +1,607 @@
+package webber
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.html
+var openAPIDocsPage string
+
+// swaggerUI holds the pinned Swagger UI release the docs page loads,
+// see swagger-ui/README.md.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var swaggerUI embed.FS
+
+var swaggerUITypes = map[string]string{
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+	"swagger-ui.css":       "text/css; charset=utf-8",
+}
+
+// Doc describes the API in the OpenAPI document served at OPENAPI_PATH.
+type Doc struct {
+	Title       string
+	Version     string
+	Description string
+
+	// SecuritySchemes are the schemes operations refer to by name
+	SecuritySchemes map[string]SecurityScheme
+	// Security lists the schemes, any of which authorizes the operations without their own
+	Security []string
+
+	routes     []docRoute
+	operations map[string]Operation
+}
+
+// SecurityScheme is an OpenAPI security scheme, such as
+// SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Operation is the documentation of a route.
+type Operation struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+	// Security overrides Doc.Security, an empty non-nil slice makes the route public
+	Security []string
+}
+
+type docRoute struct {
+	method string
+	path   string
+	req    reflect.Type
+	resp   reflect.Type
+}
+
+// Doc returns the metadata of the OpenAPI document.
+func (a *App) Doc() *Doc {
+	return &a.doc
+}
+
+// Operation documents the route registered with the given method and full path,
+// such as Operation("GET", "/users/:id", ...).
+func (d *Doc) Operation(method, path string, op Operation) {
+	if d.operations == nil {
+		d.operations = make(map[string]Operation)
+	}
+	d.operations[method+" "+path] = op
+}
+
+func (d *Doc) addRoute(method, path string, h routeHandler) {
+	// The CONNECT and TRACE routes registered by Any are not documented
+	if method == http.MethodConnect || method == http.MethodTrace {
+		return
+	}
+	d.routes = append(d.routes, docRoute{method: method, path: path, req: h.req, resp: h.resp})
+}
+
+// initOpenAPI serves the OpenAPI document at OPENAPI_PATH (/openapi.json) and
+// the Swagger UI docs page at OPENAPI_DOCS_PATH (/docs), an empty path disables
+// them. The Swagger UI files are embedded and served under the docs path.
+// They are served on the admin server, and on the public server only when
+// OPENAPI_PUBLIC is enabled.
+func (a *App) initOpenAPI() {
+	specPath := a.Config.GetString("OPENAPI_PATH", "/openapi.json")
+	if specPath == "" {
+		return
+	}
+
+	r := a.httpServer.router
+	if public, _ := a.Config.GetBool("OPENAPI_PUBLIC", false); !public {
+		if a.adminServer == nil {
+			return
+		}
+		r = a.adminServer.router
+	}
+
+	r.GET(specPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, a.OpenAPI())
+	})
+
+	if docsPath := a.Config.GetString("OPENAPI_DOCS_PATH", "/docs"); docsPath != "" {
+		assetsPath := strings.TrimSuffix(docsPath, "/")
+		specURL, _ := json.Marshal(specPath)
+		page := strings.NewReplacer("SPEC_URL", string(specURL), "ASSETS_URL", html.EscapeString(assetsPath)).Replace(openAPIDocsPage)
+
+		r.GET(docsPath, func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+		})
+
+		for name, contentType := range swaggerUITypes {
+			data, _ := swaggerUI.ReadFile("swagger-ui/" + name)
+			r.GET(assetsPath+"/"+name, func(c *gin.Context) {
+				c.Header("Cache-Control", "public, max-age=86400")
+				c.Data(http.StatusOK, contentType, data)
+			})
+		}
+	}
+}
+
+// ExportOpenAPI writes the OpenAPI document as indented JSON, the output
+// is stable so that it can be committed and diffed.
+func (a *App) ExportOpenAPI(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.OpenAPI())
+}
+
+// OpenAPI builds the OpenAPI 3.1 document of the routes of the public server.
+// The parameters, request body and response of the routes registered with
+// Handle are described from their types.
+func (a *App) OpenAPI() map[string]interface{} {
+	d := &a.doc
+	s := &schemas{components: map[string]interface{}{}, names: map[reflect.Type]string{}}
+
+	info := map[string]interface{}{"title": d.Title, "version": d.Version}
+	if d.Title == "" {
+		info["title"] = "API"
+	}
+	if d.Version == "" {
+		info["version"] = "1.0.0"
+	}
+	if d.Description != "" {
+		info["description"] = d.Description
+	}
+
+	paths := map[string]interface{}{}
+	for _, r := range d.routes {
+		p := openAPIPath(r.path)
+
+		item, ok := paths[p].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[p] = item
+		}
+
+		item[strings.ToLower(r.method)] = d.operation(s, r)
+	}
+
+	s.schema(reflect.TypeOf(Problem{}))
+
+	components := map[string]interface{}{"schemas": s.components}
+	if len(d.SecuritySchemes) > 0 {
+		components["securitySchemes"] = d.SecuritySchemes
+	}
+
+	doc := map[string]interface{}{
+		"openapi":    "3.1.0",
+		"info":       info,
+		"paths":      paths,
+		"components": components,
+	}
+	if len(d.Security) > 0 {
+		doc["security"] = securityRequirements(d.Security)
+	}
+
+	return doc
+}
+
+func (d *Doc) operation(s *schemas, r docRoute) map[string]interface{} {
+	op := map[string]interface{}{}
+
+	if o, ok := d.operations[r.method+" "+r.path]; ok {
+		setIfNotEmpty(op, "summary", o.Summary)
+		setIfNotEmpty(op, "description", o.Description)
+		setIfNotEmpty(op, "operationId", o.OperationID)
+		if len(o.Tags) > 0 {
+			op["tags"] = o.Tags
+		}
+		if o.Deprecated {
+			op["deprecated"] = true
+		}
+		if o.Security != nil {
+			op["security"] = securityRequirements(o.Security)
+		}
+	}
+
+	var params []interface{}
+	documented := map[string]bool{}
+
+	if r.req != nil {
+		var body map[string]interface{}
+		params, body = s.request(r.req, r.method)
+		if body != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": body}},
+			}
+		}
+		for _, p := range params {
+			p := p.(map[string]interface{})
+			if p["in"] == "path" {
+				documented[p["name"].(string)] = true
+			}
+		}
+	}
+
+	// Path params that are not bound to a field of the request
+	for _, segment := range strings.Split(r.path, "/") {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') || documented[segment[1:]] {
+			continue
+		}
+		params = append(params, map[string]interface{}{
+			"name": segment[1:], "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	status, success := "200", map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+	if r.resp != nil {
+		if r.resp.Kind() != reflect.Ptr && r.resp.Implements(reflect.TypeOf((*StatusCoder)(nil)).Elem()) {
+			code := reflect.Zero(r.resp).Interface().(StatusCoder).StatusCode()
+			status, success["description"] = strconv.Itoa(code), http.StatusText(code)
+		}
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": s.schema(r.resp)}}
+	}
+
+	op["responses"] = map[string]interface{}{
+		status: success,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				ProblemContentType: map[string]interface{}{"schema": s.schema(reflect.TypeOf(Problem{}))},
+			},
+		},
+	}
+
+	return op
+}
+
+// schemas builds the JSON schemas of Go types, named structs are added to
+// the components and referenced.
+type schemas struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func (s *schemas) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		// The encoding is unknown
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t, nil)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + s.component(t)}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// component adds the schema of the named struct to the components
+// and returns its name.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	base := nonIdentChars.ReplaceAllString(t.Name(), "_")
+	name := base
+	for i := 2; s.components[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+
+	// Registered first for recursive types
+	s.names[t] = name
+	s.components[name] = map[string]interface{}{}
+	s.components[name] = s.object(t, nil)
+
+	return name
+}
+
+// object returns the schema of the struct fields accepted by include, all of them if nil.
+func (s *schemas) object(t reflect.Type, include func(f reflect.StructField) bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for _, f := range structFields(t) {
+		if include != nil && !include(f) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		schema, isRequired := s.field(f)
+		properties[name] = schema
+		if isRequired {
+			required = append(required, name)
+		}
+	}
+
+	obj := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+
+	return obj
+}
+
+// field returns the schema of a struct field with the constraints
+// of its binding and validate tags, and whether it is required.
+func (s *schemas) field(f reflect.StructField) (map[string]interface{}, bool) {
+	schema := s.schema(f.Type)
+
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+
+	for _, tag := range []string{f.Tag.Get("binding"), f.Tag.Get("validate")} {
+		for _, rule := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(rule, "=")
+
+			// The following rules apply to the elements
+			if name == "dive" {
+				break
+			}
+
+			switch name {
+			case "required":
+				required = true
+			case "oneof":
+				var enum []interface{}
+				for _, v := range strings.Fields(param) {
+					enum = append(enum, enumValue(t, v))
+				}
+				schema["enum"] = enum
+			case "email":
+				schema["format"] = "email"
+			case "url", "uri":
+				schema["format"] = "uri"
+			case "uuid", "uuid4":
+				schema["format"] = "uuid"
+			case "ipv4", "ipv6":
+				schema["format"] = name
+			case "min", "gte", "max", "lte", "gt", "lt", "len":
+				setLimit(schema, t, name, param)
+			}
+		}
+	}
+
+	return schema, required
+}
+
+// setLimit sets the bound of a min, max, gte, lte, gt, lt or len rule.
+func setLimit(schema map[string]interface{}, t reflect.Type, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	var min, max string
+	switch t.Kind() {
+	case reflect.String:
+		min, max = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		min, max = "minItems", "maxItems"
+	case reflect.Map:
+		min, max = "minProperties", "maxProperties"
+	default:
+		min, max = "minimum", "maximum"
+		switch rule {
+		case "gt":
+			schema["exclusiveMinimum"] = n
+			return
+		case "lt":
+			schema["exclusiveMaximum"] = n
+			return
+		}
+	}
+
+	switch rule {
+	case "min", "gte", "gt":
+		schema[min] = n
+	case "max", "lte", "lt":
+		schema[max] = n
+	case "len":
+		schema[min], schema[max] = n, n
+	}
+}
+
+func enumValue(t reflect.Type, v string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// request returns the parameters and the body schema of the request struct of a
+// typed handler, following the sources used by bindRequest.
+func (s *schemas) request(t reflect.Type, method string) ([]interface{}, map[string]interface{}) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	hasBody := method != http.MethodGet && method != http.MethodHead && method != http.MethodDelete
+
+	var params []interface{}
+	inBody := map[string]bool{}
+
+	for _, f := range structFields(t) {
+		var in, name string
+
+		switch {
+		case tagName(f, "uri") != "":
+			in, name = "path", tagName(f, "uri")
+		case tagName(f, "header") != "":
+			in, name = "header", tagName(f, "header")
+		case tagName(f, "form") != "" && (!hasBody || tagName(f, "json") == ""):
+			in, name = "query", tagName(f, "form")
+		default:
+			inBody[fmt.Sprint(f.Index)] = hasBody
+			continue
+		}
+
+		schema, required := s.field(f)
+		param := map[string]interface{}{"name": name, "in": in, "schema": schema}
+		if required || in == "path" {
+			param["required"] = true
+		}
+		params = append(params, param)
+	}
+
+	var body map[string]interface{}
+	for _, ok := range inBody {
+		if ok {
+			body = s.object(t, func(f reflect.StructField) bool { return inBody[fmt.Sprint(f.Index)] })
+			break
+		}
+	}
+
+	return params, body
+}
+
+// tagName returns the name in the tag of the field, "" if absent or "-".
+func tagName(f reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// structFields returns the exported fields of the struct as seen by encoding/json,
+// the fields of embedded structs without a json name are promoted with their
+// index from t.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && ft.Kind() == reflect.Struct && tagName(f, "json") == "" {
+			for _, promoted := range structFields(ft) {
+				promoted.Index = append([]int{i}, promoted.Index...)
+				fields = append(fields, promoted)
+			}
+			continue
+		}
+
+		if f.IsExported() {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// openAPIPath converts the :param and *param segments of a route to {param}.
+func openAPIPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// joinPaths joins a group base path and a route path, keeping the trailing slash.
+func joinPaths(base, p string) string {
+	if p == "" {
+		return base
+	}
+
+	joined := path.Join(base, p)
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// securityRequirements returns the requirements satisfied by any of the schemes.
+func securityRequirements(names []string) []interface{} {
+	requirements := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		requirements = append(requirements, map[string]interface{}{name: []string{}})
+	}
+	return requirements
+}
+
+func setIfNotEmpty(m map[string]interface{}, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
@@ -0,0 +1,51 @@
+package webber_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+func TestOpenAPIPublic(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	// Not served on the public server by default, the paths are free for the routes
+	app.GetE("/docs", func(c *webber.Context) error {
+		c.String(http.StatusOK, "own docs")
+		return nil
+	})
+	app.Client().Get("/openapi.json").Do().Status(http.StatusNotFound)
+	app.Client().Get("/docs").Do().Status(http.StatusOK).BodyContains("own docs")
+
+	app = webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"OPENAPI_PUBLIC": "true",
+	}))
+	app.Client().Get("/openapi.json").Do().Status(http.StatusOK).BodyContains(`"openapi"`)
+	app.Client().Get("/docs").Do().Status(http.StatusOK).Header("Content-Type", "text/html; charset=utf-8").
+		BodyContains(`src="/docs/swagger-ui-bundle.js"`).BodyContains(`url: "/openapi.json"`)
+	app.Client().Get("/docs/swagger-ui-bundle.js").Do().Status(http.StatusOK).
+		Header("Content-Type", "text/javascript; charset=utf-8").BodyContains("SwaggerUIBundle")
+	app.Client().Get("/docs/swagger-ui.css").Do().Status(http.StatusOK).Header("Content-Type", "text/css; charset=utf-8")
+}
+
+func TestOpenAPIAny(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis())
+
+	app.AnyE("/any", func(c *webber.Context) error {
+		return nil
+	})
+
+	item := app.OpenAPI()["paths"].(map[string]interface{})["/any"].(map[string]interface{})
+	for _, method := range []string{"get", "post", "put", "patch", "head", "options", "delete"} {
+		if _, ok := item[method]; !ok {
+			t.Errorf("expected the %s operation", method)
+		}
+	}
+	for _, method := range []string{"connect", "trace"} {
+		if _, ok := item[method]; ok {
+			t.Errorf("expected no %s operation", method)
+		}
+	}
+}
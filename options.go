@@ -0,0 +1,83 @@
+package webber
+
+import (
+	"net"
+
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/container"
+	"github.com/xbmlz/webber/log"
+)
+
+// Option configures an App created with New.
+type Option func(*options)
+
+type options struct {
+	config       config.Config
+	configPath   string
+	logger       log.Logger
+	container    *container.Container
+	withoutDB    bool
+	withoutRedis bool
+	httpAddr     string
+	listener     net.Listener
+}
+
+// WithConfig uses the given config instead of loading it from the env files.
+func WithConfig(cfg config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithConfigPath sets the directory the env files are loaded from.
+// By default they are loaded from ./configs if it exists, or the working directory.
+func WithConfigPath(path string) Option {
+	return func(o *options) {
+		o.configPath = path
+	}
+}
+
+// WithLogger uses the given logger instead of building one from the config.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithContainer uses the given container instead of building one from the config.
+// WithoutDB, WithoutRedis and WithLogger have no effect on the given container.
+func WithContainer(c *container.Container) Option {
+	return func(o *options) {
+		o.container = c
+	}
+}
+
+// WithoutDB skips the database connection even if it is configured.
+func WithoutDB() Option {
+	return func(o *options) {
+		o.withoutDB = true
+	}
+}
+
+// WithoutRedis skips the redis connection even if it is configured.
+func WithoutRedis() Option {
+	return func(o *options) {
+		o.withoutRedis = true
+	}
+}
+
+// WithHTTPAddr sets the host:port the HTTP server listens on,
+// overriding HTTP_HOST and HTTP_PORT.
+func WithHTTPAddr(addr string) Option {
+	return func(o *options) {
+		o.httpAddr = addr
+	}
+}
+
+// WithListener serves HTTP on the given listener, overriding HTTP_LISTEN,
+// HTTP_HOST and HTTP_PORT. The listener is closed by Shutdown.
+func WithListener(ln net.Listener) Option {
+	return func(o *options) {
+		o.listener = ln
+	}
+}
@@ -0,0 +1,165 @@
+package webber
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// HeaderRequestID is the header of the request ID, it is accepted from
+	// the client and echoed in the response.
+	HeaderRequestID = "X-Request-ID"
+	// HeaderTraceparent is the W3C trace context header.
+	HeaderTraceparent = "traceparent"
+
+	requestIDKey   = "webber.request_id"
+	traceparentKey = "webber.traceparent"
+	userKey        = "webber.user"
+
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware sets the ID of the request from its X-Request-ID header,
+// or the trace ID of its traceparent header, generating one if both are missing.
+// The ID is echoed in the response and carried by the request context so that
+// it is logged with the database and redis queries.
+func requestIDMiddleware(ctx *gin.Context) {
+	traceID, ok := parseTraceparent(ctx.GetHeader(HeaderTraceparent))
+	if !ok {
+		traceID = randomHex(16)
+	}
+	// The request is a new span of the trace
+	traceparent := "00-" + traceID + "-" + randomHex(8) + "-01"
+
+	id := ctx.GetHeader(HeaderRequestID)
+	if !validRequestID(id) {
+		id = traceID
+	}
+
+	ctx.Set(requestIDKey, id)
+	ctx.Set(traceparentKey, traceparent)
+	ctx.Request = ctx.Request.WithContext(log.WithRequestID(ctx.Request.Context(), id))
+
+	ctx.Header(HeaderRequestID, id)
+	ctx.Header(HeaderTraceparent, traceparent)
+
+	ctx.Next()
+}
+
+// accessLogFields adds the request ID and user to the access log.
+func accessLogFields(ctx *gin.Context) []zapcore.Field {
+	var fields []zapcore.Field
+	if id := ctx.GetString(requestIDKey); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if user := requestUser(ctx); user != "" {
+		fields = append(fields, zap.String("user", user))
+	}
+	return fields
+}
+
+// requestLogger returns the logger of the request, logging its ID, method, route and user.
+func requestLogger(ctx *gin.Context, l log.Logger) log.Logger {
+	fields := []zapcore.Field{
+		zap.String("request_id", ctx.GetString(requestIDKey)),
+		zap.String("method", ctx.Request.Method),
+		zap.String("route", ctx.FullPath()),
+	}
+	if user := requestUser(ctx); user != "" {
+		fields = append(fields, zap.String("user", user))
+	}
+	return log.With(l, fields...)
+}
+
+// requestUser returns the user set with SetUser or by the gin basic auth.
+func requestUser(ctx *gin.Context) string {
+	if user := ctx.GetString(userKey); user != "" {
+		return user
+	}
+	return ctx.GetString(gin.AuthUserKey)
+}
+
+// RequestID returns the ID of the request, see HeaderRequestID.
+func (c *Context) RequestID() string {
+	if c.Context == nil {
+		return log.RequestID(c.base())
+	}
+	if id := c.GetString(requestIDKey); id != "" {
+		return id
+	}
+	return c.GetHeader(HeaderRequestID)
+}
+
+// Traceparent returns the W3C traceparent of the request, to be sent
+// with the requests made to other services.
+func (c *Context) Traceparent() string {
+	if c.Context == nil {
+		return ""
+	}
+	return c.GetString(traceparentKey)
+}
+
+// SetUser sets the user of the request, it is added to the request logger
+// and to the access log. It is usually called by the authentication middleware.
+func (c *Context) SetUser(user string) {
+	c.Set(userKey, user)
+
+	if c.app != nil {
+		c.Container.Logger = requestLogger(c.Context, c.app.container.Logger)
+	}
+}
+
+// parseTraceparent returns the trace ID of a version 00 traceparent header.
+func parseTraceparent(v string) (string, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", false
+	}
+
+	traceID, parentID := parts[1], parts[2]
+	if !isLowerHex(parts[0], 2) || !isLowerHex(traceID, 32) || !isLowerHex(parentID, 16) || !isLowerHex(parts[3], 2) {
+		return "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validRequestID reports whether a client request ID can be logged and echoed as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
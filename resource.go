@@ -0,0 +1,602 @@
+package webber
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultResourcePageSize    = 20
+	defaultResourceMaxPageSize = 100
+)
+
+// Resource actions passed to the Authorize hook.
+const (
+	ActionList   = "list"
+	ActionGet    = "get"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ResourceOption configures a resource registered with Resource.
+type ResourceOption func(*resource)
+
+// WithResourceFilters allows filtering the list by the given fields,
+// referred to by their JSON name: ?name=bob&role=admin&role=owner.
+func WithResourceFilters(fields ...string) ResourceOption {
+	return func(r *resource) {
+		r.filters = append(r.filters, fields...)
+	}
+}
+
+// WithResourceSorts allows sorting the list by the given fields,
+// referred to by their JSON name: ?sort=name,-created_at.
+func WithResourceSorts(fields ...string) ResourceOption {
+	return func(r *resource) {
+		r.sorts = append(r.sorts, fields...)
+	}
+}
+
+// WithResourcePageSize sets the default and maximum number of items of a page.
+func WithResourcePageSize(size, max int) ResourceOption {
+	return func(r *resource) {
+		r.pageSize = size
+		r.maxPageSize = max
+	}
+}
+
+// WithResourceBeforeCreate runs fn on the bound model before it is created.
+func WithResourceBeforeCreate(fn func(c *Context, model interface{}) error) ResourceOption {
+	return func(r *resource) {
+		r.beforeCreate = fn
+	}
+}
+
+// WithResourceAuthorize runs fn before each action, the model is nil for
+// ActionList and is the bound model for ActionCreate. ActionUpdate is authorized
+// with the stored model, then again with the body applied to it so that fields
+// such as the owner cannot be moved to a value fn rejects. A non-nil error is
+// returned to the client, such as an HTTPError with status 403.
+func WithResourceAuthorize(fn func(c *Context, action string, model interface{}) error) ResourceOption {
+	return func(r *resource) {
+		r.authorize = fn
+	}
+}
+
+// WithResourceTransform replaces each model by the value returned by fn
+// in the responses, to hide fields or return a different representation.
+func WithResourceTransform(fn func(c *Context, model interface{}) (interface{}, error)) ResourceOption {
+	return func(r *resource) {
+		r.transform = fn
+	}
+}
+
+// ResourcePage is the response of the list endpoint of a resource.
+type ResourcePage struct {
+	Items []interface{} `json:"items"`
+	// Page and Total are set with page based pagination
+	Page  int    `json:"page,omitempty"`
+	Size  int    `json:"size"`
+	Total *int64 `json:"total,omitempty"`
+	// NextCursor is set with cursor based pagination when there are more items
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type resource struct {
+	modelType reflect.Type
+	schema    *schema.Schema
+
+	filters     []string
+	sorts       []string
+	pageSize    int
+	maxPageSize int
+
+	beforeCreate func(c *Context, model interface{}) error
+	authorize    func(c *Context, action string, model interface{}) error
+	transform    func(c *Context, model interface{}) (interface{}, error)
+
+	// fields maps the JSON names to the fields of the model
+	fields map[string]*schema.Field
+	// readOnly are the fields clients cannot set: the primary key,
+	// the timestamps, the soft delete field and the associations
+	readOnly []*schema.Field
+}
+
+var resourceSchemas sync.Map
+
+// Resource registers REST endpoints over the GORM model, a pointer to a struct:
+//
+//	GET    /path        list, paginated with ?page=&size= or ?cursor=&size=
+//	GET    /path/:id    get
+//	POST   /path        create
+//	PUT    /path/:id    update all the fields
+//	PATCH  /path/:id    update the fields present in the body
+//	DELETE /path/:id    delete, soft if the model has a gorm.DeletedAt field
+//
+// Clients cannot set the primary key, the timestamps, the gorm.DeletedAt field and
+// the associations: they are ignored by create and update and rejected by PATCH.
+//
+// It panics if the model cannot be parsed or has no primary key.
+func (a *App) Resource(path string, model interface{}, opts ...ResourceOption) {
+	a.Group(path).Resource("", model, opts...)
+}
+
+// Resource registers REST endpoints over the GORM model in the group, see App.Resource.
+func (g *RouterGroup) Resource(path string, model interface{}, opts ...ResourceOption) {
+	namer := schema.Namer(schema.NamingStrategy{})
+	if db := g.app.container.DB; db != nil && db.DB != nil {
+		namer = db.NamingStrategy
+	}
+
+	s, err := schema.Parse(model, &resourceSchemas, namer)
+	if err != nil {
+		panic(fmt.Sprintf("webber: resource %s: %v", path, err))
+	}
+	if s.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("webber: resource %s: %s has no primary key", path, s.Name))
+	}
+
+	r := &resource{
+		modelType:   s.ModelType,
+		schema:      s,
+		pageSize:    defaultResourcePageSize,
+		maxPageSize: defaultResourceMaxPageSize,
+		fields:      map[string]*schema.Field{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		if f.PrimaryKey || f.AutoCreateTime > 0 || f.AutoUpdateTime > 0 || f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			r.readOnly = append(r.readOnly, f)
+		}
+		name := tagName(f.StructField, "json")
+		if name == "" {
+			name = f.Name
+		}
+		r.fields[name] = f
+	}
+
+	for _, rel := range s.Relationships.Relations {
+		r.readOnly = append(r.readOnly, rel.Field)
+	}
+
+	for _, name := range append(append([]string(nil), r.filters...), r.sorts...) {
+		if r.fields[name] == nil {
+			panic(fmt.Sprintf("webber: resource %s: unknown field %q", path, name))
+		}
+	}
+
+	// The model type documents the routes that send or receive it
+	typed := func(fn Handler, req, resp reflect.Type) routeHandler {
+		if r.transform != nil {
+			resp = nil
+		}
+		return routeHandler{fn: fn, req: req, resp: resp}
+	}
+
+	item := strings.TrimSuffix(path, "/") + "/:id"
+
+	g.GetE(path, r.list)
+	g.handle(http.MethodGet, item, typed(r.get, nil, r.modelType))
+	g.handle(http.MethodPost, path, typed(r.create, r.modelType, r.modelType))
+	g.handle(http.MethodPut, item, typed(r.update, r.modelType, r.modelType))
+	g.handle(http.MethodPatch, item, typed(r.patch, nil, r.modelType))
+	g.DeleteE(item, r.delete)
+}
+
+func (r *resource) db(c *Context) (*gorm.DB, error) {
+	if c.DB == nil || c.DB.DB == nil {
+		return nil, errDBNotConfigured
+	}
+	return c.DB.DB, nil
+}
+
+func (r *resource) new() interface{} {
+	return reflect.New(r.modelType).Interface()
+}
+
+func (r *resource) list(c *Context) error {
+	if err := r.authorizeAction(c, ActionList, nil); err != nil {
+		return err
+	}
+
+	db, err := r.db(c)
+	if err != nil {
+		return err
+	}
+
+	size, err := queryInt(c, "size", r.pageSize)
+	if err != nil {
+		return err
+	}
+	if size < 1 || size > r.maxPageSize {
+		return badRequest(fmt.Sprintf("size must be between 1 and %d", r.maxPageSize))
+	}
+
+	db = db.Model(r.new())
+
+	for _, name := range r.filters {
+		values, ok := c.GetQueryArray(name)
+		if !ok {
+			continue
+		}
+
+		f := r.fields[name]
+		args := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			arg, err := parseField(f, v)
+			if err != nil {
+				return badRequest(fmt.Sprintf("invalid %s: %v", name, err))
+			}
+			args = append(args, arg)
+		}
+
+		db = db.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Values: args})
+	}
+
+	pk := r.schema.PrioritizedPrimaryField
+	items := reflect.New(reflect.SliceOf(reflect.PointerTo(r.modelType)))
+	page := ResourcePage{Size: size}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		// Cursor pagination follows the primary key
+		if c.Query("sort") != "" {
+			return badRequest("sort cannot be used with cursor")
+		}
+
+		if cursor != "" {
+			after, err := decodeCursor(pk, cursor)
+			if err != nil {
+				return badRequest("invalid cursor")
+			}
+			db = db.Where(clause.Gt{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: after})
+		}
+
+		err = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}}).
+			Limit(size + 1).Find(items.Interface()).Error
+		if err != nil {
+			return err
+		}
+
+		if items.Elem().Len() > size {
+			items.Elem().SetLen(size)
+
+			last := items.Elem().Index(size - 1)
+			v, _ := pk.ValueOf(c, last)
+			page.NextCursor = encodeCursor(v)
+		}
+	} else {
+		p, err := queryInt(c, "page", 1)
+		if err != nil {
+			return err
+		}
+		if p < 1 {
+			return badRequest("page must be greater than 0")
+		}
+		page.Page = p
+
+		var total int64
+		if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return err
+		}
+		page.Total = &total
+
+		for _, s := range strings.Split(c.Query("sort"), ",") {
+			if s == "" {
+				continue
+			}
+
+			name, desc := strings.TrimPrefix(s, "-"), strings.HasPrefix(s, "-")
+			if !slices.Contains(r.sorts, name) {
+				return badRequest(fmt.Sprintf("cannot sort by %s", name))
+			}
+
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: r.fields[name].DBName}, Desc: desc})
+		}
+
+		// The primary key makes the order stable across pages
+		err = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}}).
+			Offset((p - 1) * size).Limit(size).Find(items.Interface()).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	page.Items = make([]interface{}, 0, items.Elem().Len())
+	for i := 0; i < items.Elem().Len(); i++ {
+		item, err := r.respond(c, items.Elem().Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		page.Items = append(page.Items, item)
+	}
+
+	return c.Respond(http.StatusOK, page)
+}
+
+func (r *resource) get(c *Context) error {
+	model, err := r.load(c, ActionGet)
+	if err != nil {
+		return err
+	}
+
+	return r.send(c, http.StatusOK, model)
+}
+
+func (r *resource) create(c *Context) error {
+	model := r.new()
+	saved := r.readOnlyValues(c, model)
+	if err := c.Decode(model); err != nil {
+		return err
+	}
+	r.restore(c, model, saved)
+
+	if err := r.authorizeAction(c, ActionCreate, model); err != nil {
+		return err
+	}
+
+	if r.beforeCreate != nil {
+		if err := r.beforeCreate(c, model); err != nil {
+			return err
+		}
+	}
+
+	db, err := r.db(c)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Omit(clause.Associations).Create(model).Error; err != nil {
+		return err
+	}
+
+	return r.send(c, http.StatusCreated, model)
+}
+
+func (r *resource) update(c *Context) error {
+	model, err := r.load(c, ActionUpdate)
+	if err != nil {
+		return err
+	}
+
+	saved := r.readOnlyValues(c, model)
+	if err := c.Decode(model); err != nil {
+		return err
+	}
+	r.restore(c, model, saved)
+
+	if err := r.authorizeAction(c, ActionUpdate, model); err != nil {
+		return err
+	}
+
+	db, _ := r.db(c)
+	if err := db.Omit(clause.Associations).Save(model).Error; err != nil {
+		return err
+	}
+
+	return r.send(c, http.StatusOK, model)
+}
+
+func (r *resource) patch(c *Context) error {
+	model, err := r.load(c, ActionUpdate)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	// The body is decoded for the names of the fields it sets, then into the model
+	decode := func(v interface{}) error {
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		return c.decodeBody(v)
+	}
+
+	var values map[string]interface{}
+	if err := decode(&values); err != nil {
+		return err
+	}
+
+	var columns []string
+	for name := range values {
+		f := r.fields[name]
+		if f == nil || !f.Updatable || slices.Contains(r.readOnly, f) {
+			return badRequest(fmt.Sprintf("field %s cannot be updated", name))
+		}
+		columns = append(columns, f.DBName)
+	}
+
+	if len(columns) == 0 {
+		return r.send(c, http.StatusOK, model)
+	}
+
+	for _, f := range r.schema.Fields {
+		if f.AutoUpdateTime > 0 {
+			columns = append(columns, f.DBName)
+		}
+	}
+
+	// The fields are applied to the current model so that the result is validated
+	if err := decode(model); err != nil {
+		return err
+	}
+	if err := r.authorizeAction(c, ActionUpdate, model); err != nil {
+		return err
+	}
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(model); err != nil {
+			return err
+		}
+	}
+
+	db, _ := r.db(c)
+	if err := db.Model(model).Select(columns).Updates(model).Error; err != nil {
+		return err
+	}
+
+	return r.send(c, http.StatusOK, model)
+}
+
+func (r *resource) delete(c *Context) error {
+	model, err := r.load(c, ActionDelete)
+	if err != nil {
+		return err
+	}
+
+	db, _ := r.db(c)
+	if err := db.Delete(model).Error; err != nil {
+		return err
+	}
+
+	c.Status(http.StatusNoContent)
+
+	return nil
+}
+
+// load loads the model with the primary key of the path and authorizes the action.
+func (r *resource) load(c *Context, action string) (interface{}, error) {
+	db, err := r.db(c)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := r.schema.PrioritizedPrimaryField
+
+	id, err := parseField(pk, c.Param("id"))
+	if err != nil {
+		return nil, &HTTPError{Status: http.StatusNotFound, Err: err}
+	}
+
+	model := r.new()
+	err = db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: id}).
+		Take(model).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.authorizeAction(c, action, model); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// readOnlyValues returns a copy of the values of the read-only fields of the model.
+func (r *resource) readOnlyValues(c *Context, model interface{}) []reflect.Value {
+	v := reflect.ValueOf(model)
+
+	values := make([]reflect.Value, len(r.readOnly))
+	for i, f := range r.readOnly {
+		values[i] = reflect.New(f.FieldType).Elem()
+		values[i].Set(f.ReflectValueOf(c, v))
+	}
+
+	return values
+}
+
+// restore sets the read-only fields of the model back to the values of readOnlyValues.
+func (r *resource) restore(c *Context, model interface{}, values []reflect.Value) {
+	v := reflect.ValueOf(model)
+
+	for i, f := range r.readOnly {
+		f.ReflectValueOf(c, v).Set(values[i])
+	}
+}
+
+func (r *resource) authorizeAction(c *Context, action string, model interface{}) error {
+	if r.authorize == nil {
+		return nil
+	}
+	return r.authorize(c, action, model)
+}
+
+func (r *resource) respond(c *Context, model interface{}) (interface{}, error) {
+	if r.transform == nil {
+		return model, nil
+	}
+	return r.transform(c, model)
+}
+
+func (r *resource) send(c *Context, status int, model interface{}) error {
+	v, err := r.respond(c, model)
+	if err != nil {
+		return err
+	}
+
+	return c.Respond(status, v)
+}
+
+// parseField converts a path or query value to the type of the field.
+func parseField(f *schema.Field, v string) (interface{}, error) {
+	switch f.IndirectFieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(v, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(v, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(v, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(v)
+	default:
+		return v, nil
+	}
+}
+
+// encodeCursor returns an opaque cursor for the primary key value.
+func encodeCursor(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(pk *schema.Field, cursor string) (interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return parseField(pk, fmt.Sprint(v))
+}
+
+func queryInt(c *Context, key string, def int) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, badRequest(fmt.Sprintf("invalid %s: %v", key, err))
+	}
+	return n, nil
+}
@@ -0,0 +1,157 @@
+package webber_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+	"gorm.io/gorm"
+)
+
+type post struct {
+	ID        uint           `json:"id" yaml:"id"`
+	Title     string         `json:"title" yaml:"title" binding:"required,min=3"`
+	Likes     int            `json:"likes" yaml:"likes"`
+	CreatedAt time.Time      `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" yaml:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" yaml:"deleted_at"`
+}
+
+func newPostApp(t *testing.T) *webbertest.App {
+	app := webbertest.New(t, webbertest.WithoutRedis())
+	if err := app.MigrateDB(&post{}); err != nil {
+		t.Fatal(err)
+	}
+	app.Resource("/posts", &post{})
+	return app
+}
+
+func TestResourceReadOnlyFields(t *testing.T) {
+	app := newPostApp(t)
+	client := app.Client()
+
+	past := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var created post
+	client.Post("/posts").WithJSON(map[string]interface{}{
+		"id": 42, "title": "first", "created_at": past, "updated_at": past, "deleted_at": past,
+	}).Do().Status(http.StatusCreated).JSON(&created)
+
+	if created.ID == 42 || created.CreatedAt.Equal(past) || created.UpdatedAt.Equal(past) || created.DeletedAt.Valid {
+		t.Errorf("expected the read-only fields to be ignored, got %+v", created)
+	}
+
+	var updated post
+	client.Put("/posts/1").WithJSON(map[string]interface{}{
+		"id": 7, "title": "replaced", "created_at": past, "deleted_at": past,
+	}).Do().Status(http.StatusOK).JSON(&updated)
+
+	if updated.ID != created.ID || !updated.CreatedAt.Equal(created.CreatedAt) || updated.DeletedAt.Valid || updated.Title != "replaced" {
+		t.Errorf("expected only the title to be replaced, got %+v", updated)
+	}
+
+	client.Get("/posts/1").Do().Status(http.StatusOK)
+	client.Get("/posts/7").Do().Status(http.StatusNotFound)
+
+	for _, field := range []string{"id", "created_at", "updated_at", "deleted_at", "unknown"} {
+		client.Patch("/posts/1").WithJSON(map[string]interface{}{field: nil}).Do().Status(http.StatusBadRequest)
+	}
+}
+
+func TestResourcePatchCodecs(t *testing.T) {
+	app := newPostApp(t)
+	client := app.Client()
+
+	client.Post("/posts").WithJSON(map[string]interface{}{"title": "first", "likes": 1}).Do().Status(http.StatusCreated)
+
+	client.Patch("/posts/1").WithBody("application/yaml", strings.NewReader("likes: 5\n")).Do().
+		Status(http.StatusOK).
+		BodyContains(`"likes":5`).
+		BodyContains(`"title":"first"`)
+
+	client.Patch("/posts/1").WithBody("application/yaml", strings.NewReader("title: ab\n")).Do().
+		Status(http.StatusUnprocessableEntity)
+	client.Patch("/posts/1").WithBody("text/plain", strings.NewReader("likes")).Do().
+		Status(http.StatusUnsupportedMediaType)
+
+	var p post
+	client.Get("/posts/1").Do().Status(http.StatusOK).JSON(&p)
+	if p.Likes != 5 || p.Title != "first" {
+		t.Errorf("unexpected post %+v", p)
+	}
+}
+
+type role struct {
+	ID       uint   `json:"id"`
+	MemberID uint   `json:"member_id"`
+	Name     string `json:"name"`
+}
+
+type member struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	OwnerID uint   `json:"owner_id"`
+	Roles   []role `json:"roles"`
+}
+
+func TestResourceAssociations(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutRedis())
+	if err := app.MigrateDB(&member{}, &role{}); err != nil {
+		t.Fatal(err)
+	}
+	app.Resource("/members", &member{})
+
+	var created member
+	app.Client().Post("/members").WithJSON(map[string]interface{}{
+		"name": "bob", "roles": []map[string]interface{}{{"id": 99, "name": "admin"}},
+	}).Do().Status(http.StatusCreated).JSON(&created)
+
+	if len(created.Roles) != 0 {
+		t.Errorf("expected the roles to be ignored, got %+v", created.Roles)
+	}
+
+	app.Client().Put("/members/1").WithJSON(map[string]interface{}{
+		"name": "bob", "roles": []map[string]interface{}{{"id": 100, "name": "admin"}},
+	}).Do().Status(http.StatusOK)
+	app.Client().Patch("/members/1").WithJSON(map[string]interface{}{"roles": []interface{}{}}).Do().
+		Status(http.StatusBadRequest)
+
+	var roles int64
+	if err := app.Container().DB.Model(&role{}).Count(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if roles != 0 {
+		t.Errorf("expected no role to be created, got %d", roles)
+	}
+}
+
+func TestResourceAuthorizeUpdate(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutRedis())
+	if err := app.MigrateDB(&member{}, &role{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the members of owner 1 can be changed
+	app.Resource("/members", &member{}, webber.WithResourceAuthorize(func(c *webber.Context, action string, model interface{}) error {
+		if m, ok := model.(*member); ok && m.OwnerID != 1 {
+			return webber.NewHTTPError(http.StatusForbidden, "forbidden", "not your member")
+		}
+		return nil
+	}))
+
+	client := app.Client()
+	client.Post("/members").WithJSON(map[string]interface{}{"name": "bob", "owner_id": 1}).Do().Status(http.StatusCreated)
+
+	client.Put("/members/1").WithJSON(map[string]interface{}{"name": "bob", "owner_id": 2}).Do().Status(http.StatusForbidden)
+	client.Patch("/members/1").WithJSON(map[string]interface{}{"owner_id": 2}).Do().Status(http.StatusForbidden)
+	client.Patch("/members/1").WithJSON(map[string]interface{}{"name": "ann"}).Do().Status(http.StatusOK)
+
+	var m member
+	client.Get("/members/1").Do().Status(http.StatusOK).JSON(&m)
+	if m.OwnerID != 1 || m.Name != "ann" {
+		t.Errorf("expected only the name to change, got %+v", m)
+	}
+}
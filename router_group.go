@@ -0,0 +1,110 @@
+package webber
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// anyMethods are the methods registered by Any.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodHead,
+	http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
+
+// RouterGroup is a group of routes sharing a path prefix and middleware
+// whose handlers receive a *Context.
+type RouterGroup struct {
+	app    *App
+	server *httpServer
+	group  *gin.RouterGroup
+}
+
+func (g *RouterGroup) addRoute(method, path string, handler Handler) {
+	g.handle(method, path, routeHandler{fn: handler})
+}
+
+func (g *RouterGroup) handle(method, path string, h routeHandler) {
+	g.server.registered = true
+
+	g.group.Handle(method, path, g.app.ginHandler(h.fn))
+
+	// Only the routes of the public server are documented
+	if g.server == g.app.httpServer {
+		g.app.doc.addRoute(method, joinPaths(g.group.BasePath(), path), h)
+	}
+}
+
+func (g *RouterGroup) Get(path string, handler HandlerFunc) {
+	g.addRoute(http.MethodGet, path, handler.handler())
+}
+
+// GetE registers a handler returning an error, see Handler.
+func (g *RouterGroup) GetE(path string, handler Handler) {
+	g.addRoute(http.MethodGet, path, handler)
+}
+
+func (g *RouterGroup) Post(path string, handler HandlerFunc) {
+	g.addRoute(http.MethodPost, path, handler.handler())
+}
+
+// PostE registers a handler returning an error, see Handler.
+func (g *RouterGroup) PostE(path string, handler Handler) {
+	g.addRoute(http.MethodPost, path, handler)
+}
+
+func (g *RouterGroup) Put(path string, handler HandlerFunc) {
+	g.addRoute(http.MethodPut, path, handler.handler())
+}
+
+// PutE registers a handler returning an error, see Handler.
+func (g *RouterGroup) PutE(path string, handler Handler) {
+	g.addRoute(http.MethodPut, path, handler)
+}
+
+func (g *RouterGroup) Patch(path string, handler HandlerFunc) {
+	g.addRoute(http.MethodPatch, path, handler.handler())
+}
+
+// PatchE registers a handler returning an error, see Handler.
+func (g *RouterGroup) PatchE(path string, handler Handler) {
+	g.addRoute(http.MethodPatch, path, handler)
+}
+
+func (g *RouterGroup) Delete(path string, handler HandlerFunc) {
+	g.addRoute(http.MethodDelete, path, handler.handler())
+}
+
+// DeleteE registers a handler returning an error, see Handler.
+func (g *RouterGroup) DeleteE(path string, handler Handler) {
+	g.addRoute(http.MethodDelete, path, handler)
+}
+
+// Any registers the handler for all the HTTP methods.
+func (g *RouterGroup) Any(path string, handler HandlerFunc) {
+	g.AnyE(path, handler.handler())
+}
+
+// AnyE registers a handler returning an error for all the HTTP methods.
+func (g *RouterGroup) AnyE(path string, handler Handler) {
+	for _, method := range anyMethods {
+		g.addRoute(method, path, handler)
+	}
+}
+
+// Use adds middleware to the group, it only applies to the routes
+// added afterwards.
+func (g *RouterGroup) Use(middleware ...Middleware) {
+	g.group.Use(g.app.ginMiddleware(middleware)...)
+}
+
+// Group returns a nested group with the path prefix appended to the
+// prefix of g, and the middleware appended to the middleware of g.
+func (g *RouterGroup) Group(prefix string, middleware ...Middleware) *RouterGroup {
+	return &RouterGroup{app: g.app, server: g.server, group: g.group.Group(prefix, g.app.ginMiddleware(middleware)...)}
+}
+
+// BasePath returns the path prefix of the group.
+func (g *RouterGroup) BasePath() string {
+	return g.group.BasePath()
+}
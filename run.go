@@ -0,0 +1,227 @@
+package webber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xbmlz/webber/config"
+)
+
+// Run starts the App and blocks until SIGINT or SIGTERM is received
+// or one of the components fails. The returned error is also logged.
+// SIGHUP reloads the config and SIGUSR2 replaces the process with a
+// new one started from the same binary, see Upgrade.
+func (a *App) Run() error {
+	// Create a context that is canceled on receiving termination signals
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := a.serve(ctx)
+	if err != nil {
+		a.Logger().Errorf("Error running app: %v", err)
+	}
+
+	return err
+}
+
+// serve runs the App until ctx is done. Meanwhile SIGHUP reloads the config
+// and SIGUSR2 upgrades the process, which then drains. It is used by Run
+// and the serve command.
+func (a *App) serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	if upgradeSignal != nil {
+		signal.Notify(sigs, upgradeSignal)
+	}
+	defer signal.Stop(sigs)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-sigs:
+				if sig == syscall.SIGHUP {
+					if err := a.ReloadConfig(); err != nil {
+						a.Logger().Errorf("Error reloading config: %v", err)
+					}
+					continue
+				}
+
+				if err := a.Upgrade(); err != nil {
+					a.Logger().Errorf("Error upgrading: %v", err)
+					continue
+				}
+
+				// The new process serves the requests, drain this one
+				cancel()
+			}
+		}
+	}()
+
+	return a.RunContext(ctx)
+}
+
+// RunContext starts the App and blocks until ctx is done or one of the
+// components fails, then shuts the App down. Signal handling is left to the caller.
+// It returns the first fatal component error joined with any shutdown error.
+func (a *App) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if autoMigrate, _ := a.Config.GetBool("DB_AUTO_MIGRATE", true); autoMigrate {
+		if err := errors.Join(a.migrate(), a.seed()); err != nil {
+			return errors.Join(fmt.Errorf("migrate: %w", err), a.shutdownWithTimeout(ctx))
+		}
+	}
+
+	if err := a.runHooks(ctx, a.lifecycle.start); err != nil {
+		return errors.Join(err, a.shutdownWithTimeout(ctx))
+	}
+
+	a.serveHealth()
+
+	servers := a.servers()
+
+	for _, s := range servers {
+		if err := s.Listen(); err != nil {
+			return errors.Join(fmt.Errorf("%s server: %w", s.name, err), a.shutdownWithTimeout(ctx))
+		}
+	}
+
+	if a.grpc != nil {
+		if err := a.grpc.Listen(); err != nil {
+			return errors.Join(fmt.Errorf("gRPC server: %w", err), a.shutdownWithTimeout(ctx))
+		}
+	}
+
+	g := &runGroup{cancel: cancel}
+
+	for _, s := range servers {
+		g.Go(s.name+" server", func() error {
+			return s.Run(a.container)
+		})
+	}
+
+	if a.grpc != nil && a.grpc.listener != nil {
+		g.Go(grpcServerName+" server", a.grpc.Run)
+	}
+
+	if a.cronRegistered {
+		// The scheduler runs in its own goroutine and is stopped by Shutdown
+		a.cron.Start()
+	}
+
+	a.startWorkers(ctx)
+	a.hub.start(ctx)
+	a.startSSE(ctx)
+
+	if w, ok := a.Config.(configWatcher); ok {
+		if watch, _ := a.Config.GetBool("CONFIG_WATCH", false); watch {
+			interval, _ := config.GetDuration(a.Config, "CONFIG_WATCH_INTERVAL", defaultConfigWatchInterval)
+			g.Go("config watcher", func() error {
+				w.Watch(ctx, interval)
+				return nil
+			})
+		}
+	}
+
+	if err := a.runHooks(ctx, a.lifecycle.ready); err != nil {
+		g.fail(err)
+	} else {
+		a.health.ready.Store(true)
+		if a.grpc != nil {
+			a.grpc.health.Resume()
+		}
+
+		if err := a.notifyUpgradeReady(); err != nil {
+			a.Logger().Errorf("Error notifying the previous process: %v", err)
+		}
+	}
+
+	<-ctx.Done()
+
+	err := a.shutdownWithTimeout(ctx)
+
+	g.wg.Wait()
+
+	return errors.Join(g.err, err)
+}
+
+// shutdownWithTimeout shuts the App down with SHUTDOWN_TIMEOUT,
+// ctx is only used for its values.
+func (a *App) shutdownWithTimeout(ctx context.Context) error {
+	shutdownCtx, done := context.WithTimeout(context.WithoutCancel(ctx), a.shutdownTimeout)
+	defer done()
+
+	return a.Shutdown(shutdownCtx)
+}
+
+// runGroup runs the long-lived components of the App and
+// cancels all of them as soon as one fails.
+type runGroup struct {
+	wg     sync.WaitGroup
+	cancel context.CancelCauseFunc
+
+	once sync.Once
+	err  error
+}
+
+// Go runs fn in a new goroutine, a non-nil error cancels the group.
+func (g *runGroup) Go(name string, fn func() error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		if err := fn(); err != nil {
+			g.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+// fail records the first error and cancels the group.
+func (g *runGroup) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		g.cancel(err)
+	})
+}
+
+// servers returns the HTTP servers that have to be started.
+func (a *App) servers() []*httpServer {
+	var servers []*httpServer
+	if a.httpServer.registered {
+		servers = append(servers, a.httpServer)
+	}
+	if a.adminServer != nil {
+		servers = append(servers, a.adminServer)
+	}
+	return servers
+}
+
+const defaultConfigWatchInterval = 5 * time.Second
+
+// configWatcher is implemented by configs that reload when their files change.
+type configWatcher interface {
+	Watch(ctx context.Context, interval time.Duration)
+}
+
+// ReloadConfig reloads the config and notifies its OnChange subscribers,
+// such as the logger level, the database pool sizes and the cron schedules.
+func (a *App) ReloadConfig() error {
+	r, ok := a.Config.(config.Reloader)
+	if !ok {
+		return errors.New("config does not support reloading")
+	}
+	return r.Reload()
+}
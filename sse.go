@@ -0,0 +1,491 @@
+package webber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSSEHeartbeat = 15 * time.Second
+	defaultSSERetry     = 3 * time.Second
+	defaultSSEBuffer    = 64
+	defaultSSEHistory   = 100
+	defaultSSEChannel   = "webber:sse:"
+)
+
+var errStreamClosed = errors.New("event stream closed")
+
+// SSEEvent is a Server-Sent Event. Data is sent as is if it is a string or
+// a []byte, as JSON otherwise.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	// Retry is the delay before the client reconnects, not sent if zero
+	Retry time.Duration
+}
+
+// format returns the event in the text/event-stream format.
+func (e SSEEvent) format() ([]byte, error) {
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: " + sseLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + sseLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// sseLine removes the line breaks that would end a field.
+func sseLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEStream writes Server-Sent Events to the response. A comment is sent
+// every SSE_HEARTBEAT to keep the connection open, the stream is closed
+// when the handler returns.
+type SSEStream struct {
+	w           gin.ResponseWriter
+	lastEventID string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// SSE starts an event stream, the response headers are sent with the retry
+// hint SSE_RETRY. Calling it again returns the same stream. The stream context
+// is done when the client disconnects or the App shuts down.
+func (c *Context) SSE() *SSEStream {
+	if c.stream != nil {
+		return c.stream
+	}
+
+	heartbeat, retry := defaultSSEHeartbeat, defaultSSERetry
+	if c.app != nil {
+		heartbeat, retry = c.app.sse.heartbeat, c.app.sse.retry
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	s := &SSEStream{
+		w:           c.Writer,
+		lastEventID: c.GetHeader("Last-Event-ID"),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	c.stream = s
+
+	if c.app != nil && !c.app.sse.add(s) {
+		// The App is shutting down
+		cancel()
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// Disables the response buffering of nginx
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	s.write([]byte("retry: " + strconv.FormatInt(retry.Milliseconds(), 10) + "\n\n"))
+
+	go s.heartbeat(heartbeat)
+
+	return s
+}
+
+func (s *SSEStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.write([]byte(": heartbeat\n\n")); err != nil {
+				return
+			}
+		case <-s.ctx.Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *SSEStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.ctx.Err() != nil {
+		return errStreamClosed
+	}
+
+	if _, err := s.w.Write(b); err != nil {
+		s.cancel()
+		return err
+	}
+	s.w.Flush()
+
+	return nil
+}
+
+// close stops the stream, nothing is written once it returns.
+func (s *SSEStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		s.cancel()
+		close(s.done)
+	}
+}
+
+// Send writes the event.
+func (s *SSEStream) Send(e SSEEvent) error {
+	b, err := e.format()
+	if err != nil {
+		return err
+	}
+	return s.write(b)
+}
+
+// LastEventID returns the Last-Event-ID header sent by a reconnecting client.
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Context returns the context of the stream.
+func (s *SSEStream) Context() context.Context {
+	return s.ctx
+}
+
+// sseStreams tracks the event streams and the broadcasters of an App.
+type sseStreams struct {
+	heartbeat time.Duration
+	retry     time.Duration
+	buffer    int
+	history   int
+	channel   string
+
+	mu           sync.Mutex
+	streams      map[*SSEStream]struct{}
+	broadcasters map[string]*Broadcaster
+	// ctx is set by start, the broadcasters subscribe to Redis once it is set
+	ctx     context.Context
+	closing bool
+}
+
+func (a *App) initSSE() {
+	a.sse.heartbeat = a.positiveDuration("SSE_HEARTBEAT", defaultSSEHeartbeat)
+	a.sse.retry = a.positiveDuration("SSE_RETRY", defaultSSERetry)
+	a.sse.buffer = a.positiveInt("SSE_BUFFER", defaultSSEBuffer)
+	a.sse.history, _ = a.Config.GetInt("SSE_HISTORY", defaultSSEHistory)
+	a.sse.channel = a.Config.GetString("SSE_REDIS_CHANNEL", defaultSSEChannel)
+	a.sse.streams = map[*SSEStream]struct{}{}
+	a.sse.broadcasters = map[string]*Broadcaster{}
+}
+
+func (s *sseStreams) add(stream *SSEStream) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+
+	s.streams[stream] = struct{}{}
+	context.AfterFunc(stream.ctx, func() {
+		s.mu.Lock()
+		delete(s.streams, stream)
+		s.mu.Unlock()
+	})
+
+	return true
+}
+
+// startSSE subscribes the broadcasters to Redis, those created later subscribe
+// on creation.
+func (a *App) startSSE(ctx context.Context) {
+	a.sse.mu.Lock()
+	defer a.sse.mu.Unlock()
+
+	if a.sse.closing || a.container.Redis == nil {
+		return
+	}
+
+	a.sse.ctx = ctx
+	for _, b := range a.sse.broadcasters {
+		b.subscribe(ctx)
+	}
+}
+
+// close ends the event streams and stops the Redis subscriptions.
+func (s *sseStreams) close() {
+	s.mu.Lock()
+	s.closing = true
+	streams := make([]*SSEStream, 0, len(s.streams))
+	for stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	broadcasters := make([]*Broadcaster, 0, len(s.broadcasters))
+	for _, b := range s.broadcasters {
+		broadcasters = append(broadcasters, b)
+	}
+	s.mu.Unlock()
+
+	for _, stream := range streams {
+		stream.cancel()
+	}
+
+	for _, b := range broadcasters {
+		if b.cancel != nil {
+			b.cancel()
+			<-b.done
+		}
+	}
+}
+
+// Broadcaster returns the broadcaster of the topic, it is created on first use.
+// When Redis is configured, the events are published to SSE_REDIS_CHANNEL
+// followed by the topic so that the subscribers of every instance receive them,
+// the App receives the events of the other instances once it runs.
+func (a *App) Broadcaster(topic string) *Broadcaster {
+	a.sse.mu.Lock()
+	defer a.sse.mu.Unlock()
+
+	if b, ok := a.sse.broadcasters[topic]; ok {
+		return b
+	}
+
+	b := &Broadcaster{
+		app:         a,
+		topic:       topic,
+		id:          randomHex(8),
+		subscribers: map[*sseSubscriber]struct{}{},
+	}
+	a.sse.broadcasters[topic] = b
+
+	if a.sse.ctx != nil && !a.sse.closing {
+		b.subscribe(a.sse.ctx)
+	}
+
+	return b
+}
+
+// Broadcaster fans events out to the streams subscribed to a topic. It keeps
+// the last SSE_HISTORY events so that reconnecting clients receive the events
+// they missed. A subscriber whose buffer of SSE_BUFFER events is full is evicted,
+// its stream is closed and the client resumes from its last event.
+type Broadcaster struct {
+	app   *App
+	topic string
+	// id identifies the instance in the event IDs and in the messages published to Redis
+	id  string
+	seq atomic.Uint64
+
+	mu          sync.Mutex
+	subscribers map[*sseSubscriber]struct{}
+	history     []sseFrame
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type sseFrame struct {
+	ID    string `json:"id"`
+	Frame []byte `json:"frame"`
+}
+
+// sseMessage is an event published to Redis.
+type sseMessage struct {
+	Origin string `json:"origin"`
+	sseFrame
+}
+
+type sseSubscriber struct {
+	frames  chan sseFrame
+	evicted chan struct{}
+}
+
+// Publish sends the event to the subscribers, an ID is generated if it has none.
+func (b *Broadcaster) Publish(ctx context.Context, e SSEEvent) error {
+	if e.ID == "" {
+		e.ID = fmt.Sprintf("%s-%d", b.id, b.seq.Add(1))
+	}
+
+	frame, err := e.format()
+	if err != nil {
+		return err
+	}
+
+	f := sseFrame{ID: e.ID, Frame: frame}
+	b.deliver(f)
+
+	if b.app.container.Redis == nil {
+		return nil
+	}
+
+	msg, err := json.Marshal(sseMessage{Origin: b.id, sseFrame: f})
+	if err != nil {
+		return err
+	}
+
+	return b.app.container.Redis.Publish(ctx, b.app.sse.channel+b.topic, msg).Err()
+}
+
+func (b *Broadcaster) deliver(f sseFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.app.sse.history > 0 {
+		b.history = append(b.history, f)
+		if len(b.history) > b.app.sse.history {
+			b.history = b.history[len(b.history)-b.app.sse.history:]
+		}
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.frames <- f:
+		default:
+			b.app.Logger().Warnf("Evicting slow subscriber of %s events", b.topic)
+			delete(b.subscribers, sub)
+			close(sub.evicted)
+		}
+	}
+}
+
+// Subscribers returns the number of local subscribers.
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Serve streams the events of the topic to the client until it disconnects,
+// it is evicted or the App shuts down. The events following the Last-Event-ID
+// of a reconnecting client are sent first.
+func (b *Broadcaster) Serve(c *Context) error {
+	stream := c.SSE()
+
+	sub := &sseSubscriber{
+		frames:  make(chan sseFrame, b.app.sse.buffer),
+		evicted: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	var missed []sseFrame
+	if id := stream.LastEventID(); id != "" {
+		for i, f := range b.history {
+			if f.ID == id {
+				missed = append(missed, b.history[i+1:]...)
+				break
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+	}()
+
+	for _, f := range missed {
+		if err := stream.write(f.Frame); err != nil {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case f := <-sub.frames:
+			if err := stream.write(f.Frame); err != nil {
+				return nil
+			}
+		case <-sub.evicted:
+			return nil
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// subscribe receives the events published by the other instances.
+func (b *Broadcaster) subscribe(ctx context.Context) {
+	ctx, b.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	b.done = make(chan struct{})
+
+	sub := b.app.container.Redis.Subscribe(ctx, b.app.sse.channel+b.topic)
+
+	go func() {
+		defer close(b.done)
+		defer sub.Close()
+
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				var msg sseMessage
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					b.app.Logger().Errorf("Invalid %s event: %v", b.topic, err)
+					continue
+				}
+				if msg.Origin == b.id {
+					continue
+				}
+
+				b.deliver(msg.sseFrame)
+			}
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package webber_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+func TestBroadcasterSubscribesOnRun(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB())
+
+	app.Broadcaster("news")
+	time.Sleep(50 * time.Millisecond)
+
+	// The App does not run, nothing subscribes to Redis
+	if n := app.MiniRedis().PubSubNumSub("webber:sse:news")["webber:sse:news"]; n != 0 {
+		t.Errorf("expected no subscription before the App runs, got %d", n)
+	}
+}
+
+func TestSSEInvalidConfig(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"SSE_HEARTBEAT": "-1s",
+		"SSE_RETRY":     "soon",
+		"SSE_BUFFER":    "-1",
+	}))
+
+	app.GetE("/events", func(c *webber.Context) error {
+		return app.Broadcaster("news").Serve(c)
+	})
+
+	for _, key := range []string{"SSE_HEARTBEAT", "SSE_RETRY", "SSE_BUFFER"} {
+		if app.Logs().FilterMessageSnippet("Invalid "+key).Len() != 1 {
+			t.Errorf("expected the invalid %s to be logged", key)
+		}
+	}
+
+	// The client disconnects shortly, the stream starts with the default retry hint
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "retry: 3000") {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
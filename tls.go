@@ -0,0 +1,236 @@
+package webber
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xbmlz/webber/log"
+)
+
+// certCheckInterval is the minimum duration between two checks of the
+// certificate files for changes.
+const certCheckInterval = 5 * time.Second
+
+// tlsSettings are the TLS settings of an httpServer, read from
+//
+//	TLS_CLIENT_CA        PEM file of the CAs client certificates are verified against
+//	TLS_CLIENT_AUTH      require (default with TLS_CLIENT_CA) or verify_if_given
+//	TLS_MIN_VERSION      1.0, 1.1, 1.2 (default) or 1.3
+//	TLS_CIPHER_SUITES    comma separated names of the cipher suites, such as
+//	                     TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, ignored by TLS 1.3
+type tlsSettings struct {
+	clientCA     string
+	clientAuth   string
+	minVersion   string
+	cipherSuites string
+}
+
+// tlsConfig builds the TLS configuration of the server, the certificate
+// pair is reloaded when the files change.
+func (s *httpServer) tlsConfig() (*tls.Config, error) {
+	certs, err := newCertReloader(s.certFile, s.keyFile, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if s.tls.minVersion != "" {
+		versions := map[string]uint16{
+			"1.0": tls.VersionTLS10,
+			"1.1": tls.VersionTLS11,
+			"1.2": tls.VersionTLS12,
+			"1.3": tls.VersionTLS13,
+		}
+
+		v, ok := versions[s.tls.minVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS_MIN_VERSION %q", s.tls.minVersion)
+		}
+		cfg.MinVersion = v
+	}
+
+	if s.tls.cipherSuites != "" {
+		ids, err := cipherSuites(s.tls.cipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CipherSuites = ids
+	}
+
+	if s.tls.clientCA == "" {
+		if s.tls.clientAuth != "" {
+			return nil, errors.New("TLS_CLIENT_AUTH requires TLS_CLIENT_CA")
+		}
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(s.tls.clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", s.tls.clientCA, err)
+	}
+
+	cfg.ClientCAs = x509.NewCertPool()
+	if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("error loading %s: no certificate found", s.tls.clientCA)
+	}
+
+	switch s.tls.clientAuth {
+	case "", "require":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case "verify_if_given":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("invalid TLS_CLIENT_AUTH %q", s.tls.clientAuth)
+	}
+
+	return cfg, nil
+}
+
+// cipherSuites returns the ids of the comma separated cipher suite names.
+func cipherSuites(names string) ([]uint16, error) {
+	known := map[string]uint16{}
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// certReloader serves a certificate pair and reloads it when the files change.
+// A pair that fails to load is logged and the previous one is kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   log.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger log.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= certCheckInterval {
+		r.checkedAt = time.Now()
+
+		if modTime, err := r.lastModified(); err == nil && !modTime.Equal(r.modTime) {
+			if err := r.load(); err != nil {
+				r.logger.Errorf("Error reloading TLS certificate: %v", err)
+			} else {
+				r.logger.Infof("Reloaded TLS certificate %s", r.certFile)
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+// load loads the certificate pair, r.mu must be held unless r is not shared yet.
+func (r *certReloader) load() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading %s: %w", r.certFile, err)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+
+	return nil
+}
+
+// lastModified returns the latest modification time of the two files.
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error loading %s: %w", name, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// PeerIdentity is the identity of a client authenticated with a certificate.
+type PeerIdentity struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+
+	// Certificate is the verified client certificate
+	Certificate *x509.Certificate
+}
+
+// PeerIdentity returns the identity of the client if it presented
+// a certificate verified against TLS_CLIENT_CA.
+func (c *Context) PeerIdentity() (*PeerIdentity, bool) {
+	if c.Context == nil || c.Request == nil || c.Request.TLS == nil {
+		return nil, false
+	}
+
+	chains := c.Request.TLS.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, false
+	}
+
+	cert := chains[0][0]
+
+	return &PeerIdentity{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+		Certificate:    cert,
+	}, true
+}
@@ -0,0 +1,186 @@
+//go:build linux
+
+package webber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// envUpgradeFds lists the names of the servers whose listeners are
+	// passed to the new process, starting at file descriptor 3
+	envUpgradeFds = "WEBBER_UPGRADE_FDS"
+	// envUpgradeReadyFd is the pipe the new process writes to once it is ready
+	envUpgradeReadyFd = "WEBBER_UPGRADE_READY_FD"
+)
+
+// upgradeSignal triggers an Upgrade when received by Run.
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// Upgrade re-executes the binary and passes it the listeners of the running
+// servers, so that no connection is refused while the binary is replaced.
+// It returns once the new process has run its ready hooks, the caller is then
+// expected to shut this App down, which Run does on SIGUSR2.
+// The App keeps serving if the new process fails or is not ready within UPGRADE_TIMEOUT.
+func (a *App) Upgrade() error {
+	if !a.upgrading.CompareAndSwap(false, true) {
+		return errors.New("upgrade already in progress")
+	}
+	defer a.upgrading.Store(false)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var (
+		names []string
+		files []*os.File
+	)
+
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	listeners := map[string]net.Listener{}
+	for _, s := range a.servers() {
+		if s.listener != nil {
+			listeners[s.name] = s.listener
+		}
+	}
+	if a.grpc != nil && a.grpc.listener != nil {
+		listeners[grpcServerName] = a.grpc.listener
+	}
+
+	for name, ln := range listeners {
+		l, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("%s server listener cannot be passed to a new process", name)
+		}
+
+		f, err := l.File()
+		if err != nil {
+			return err
+		}
+
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = append(a.upgradeEnv(),
+		envUpgradeFds+"="+strings.Join(names, ":"),
+		envUpgradeReadyFd+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	a.Logger().Infof("Upgrade: started new process %d", cmd.Process.Pid)
+
+	ready := make(chan error, 1)
+	go func() {
+		// The pipe is closed without a write if the new process exits
+		_, err := r.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = errors.New("new process exited before it was ready")
+		}
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(a.upgradeTimeout):
+		err = fmt.Errorf("new process not ready after %s", a.upgradeTimeout)
+	}
+
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	// The new process removes the socket files when it stops
+	for _, s := range a.servers() {
+		if ul, ok := s.listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	a.Logger().Infof("Upgrade: new process %d is ready", cmd.Process.Pid)
+
+	return nil
+}
+
+// upgradeEnv returns the environment of the new process, without the
+// variables describing the file descriptors passed to this process and
+// without the variables loaded from the env files, which the new process
+// loads again so that their changes are applied.
+func (a *App) upgradeEnv() []string {
+	environ := os.Environ()
+	if e, ok := a.Config.(interface{ ProcessEnviron() []string }); ok {
+		environ = e.ProcessEnviron()
+	}
+
+	var env []string
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case envUpgradeFds, envUpgradeReadyFd, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// notifyUpgradeReady tells the parent process that started this one
+// with Upgrade that it can shut down, and takes over the socket files.
+func (a *App) notifyUpgradeReady() error {
+	v := os.Getenv(envUpgradeReadyFd)
+	if v == "" {
+		return nil
+	}
+
+	for _, s := range a.servers() {
+		if ul, ok := s.listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(true)
+		}
+	}
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", envUpgradeReadyFd, err)
+	}
+
+	os.Unsetenv(envUpgradeReadyFd)
+
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer f.Close()
+
+	_, err = f.Write([]byte{1})
+
+	return err
+}
@@ -0,0 +1,157 @@
+//go:build linux
+
+package webber_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+)
+
+// upgradeChildEnv marks the process started by Upgrade in TestUpgrade,
+// it serves the inherited listener instead of running the tests.
+const upgradeChildEnv = "WEBBER_TEST_UPGRADE_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(upgradeChildEnv) != "" {
+		runUpgradeChild()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+type upgradeReport struct {
+	Pid int               `json:"pid"`
+	Env map[string]string `json:"env"`
+}
+
+// reportUpgrade reports the pid and the environment of the process serving the request.
+func reportUpgrade(c *webber.Context) {
+	env := map[string]string{}
+	for _, k := range []string{"UPGRADE_TEST_PROCESS", "UPGRADE_TEST_FILE", "LISTEN_PID", "LISTEN_FDS", "WEBBER_UPGRADE_READY_FD"} {
+		if v, ok := os.LookupEnv(k); ok {
+			env[k] = v
+		}
+	}
+	c.JSON(http.StatusOK, upgradeReport{Pid: os.Getpid(), Env: env})
+}
+
+// upgradeClient opens a new connection for every request, so that they
+// are accepted by the process currently serving the listener.
+var upgradeClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+
+// getUpgradeReport requests the report of the process serving the listener.
+func getUpgradeReport(t *testing.T, url string) upgradeReport {
+	t.Helper()
+
+	res, err := upgradeClient.Get(url + "/report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var report upgradeReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// runUpgradeChild serves the reports until it is stopped.
+func runUpgradeChild() {
+	app := webber.New(webber.WithoutDB(), webber.WithoutRedis(),
+		webber.WithConfig(config.NewMap(map[string]string{"GIN_MODE": "test"})),
+		webber.WithLogger(log.NewWithZap(zap.NewNop())))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	app.Get("/report", reportUpgrade)
+	app.Get("/stop", func(c *webber.Context) {
+		cancel()
+	})
+
+	app.RunContext(ctx)
+}
+
+func TestUpgrade(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("GIN_MODE=test\nUPGRADE_TEST_FILE=file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("GIN_MODE")
+		os.Unsetenv("UPGRADE_TEST_FILE")
+	})
+
+	t.Setenv(upgradeChildEnv, "1")
+	t.Setenv("UPGRADE_TEST_PROCESS", "process")
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String()
+
+	logger := log.NewWithZap(zap.NewNop())
+	app := webber.New(webber.WithoutDB(), webber.WithoutRedis(), webber.WithListener(ln),
+		webber.WithConfig(config.New(dir, logger)), webber.WithLogger(logger))
+	app.Get("/report", reportUpgrade)
+
+	ready := make(chan struct{})
+	app.OnReady(func(context.Context) error {
+		close(ready)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- app.RunContext(ctx) }()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("the App stopped before being ready: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the App is not ready")
+	}
+
+	if report := getUpgradeReport(t, url); report.Pid != os.Getpid() {
+		t.Fatalf("expected the request to be served by this process, got %d", report.Pid)
+	}
+
+	if err := app.Upgrade(); err != nil {
+		t.Fatal(err)
+	}
+	defer upgradeClient.Get(url + "/stop")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The listener is served by the new process once this one is stopped
+	report := getUpgradeReport(t, url)
+	if report.Pid == os.Getpid() {
+		t.Error("expected the request to be served by the new process")
+	}
+	// The process environment is passed, the env files and file descriptors are not
+	want := map[string]string{"UPGRADE_TEST_PROCESS": "process"}
+	if len(report.Env) != len(want) || report.Env["UPGRADE_TEST_PROCESS"] != "process" {
+		t.Errorf("expected the environment of the new process to be %v, got %v", want, report.Env)
+	}
+}
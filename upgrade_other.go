@@ -0,0 +1,20 @@
+//go:build !linux
+
+package webber
+
+import (
+	"errors"
+	"os"
+)
+
+// upgradeSignal is not available outside linux.
+var upgradeSignal os.Signal
+
+// Upgrade is only supported on linux.
+func (a *App) Upgrade() error {
+	return errors.New("upgrade is only supported on linux")
+}
+
+func (a *App) notifyUpgradeReady() error {
+	return nil
+}
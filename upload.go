@@ -0,0 +1,236 @@
+package webber
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xbmlz/webber/datasource/storage"
+)
+
+// sniffLen is the number of bytes read to detect the type of an upload.
+const sniffLen = 512
+
+var (
+	errNoStorage      = errors.New("storage is not configured, see STORAGE_DRIVER")
+	errUploadTooLarge = errors.New("upload too large")
+)
+
+// UploadOptions are the options of SaveUpload.
+type UploadOptions struct {
+	// Key is the storage key of the file, a random key with the extension
+	// of the file name is generated under Prefix if empty
+	Key    string
+	Prefix string
+	// MaxSize is the maximum size of the file in bytes, unlimited if zero
+	MaxSize int64
+	// AllowedTypes are the accepted MIME types such as "image/png" or "image/*",
+	// any type is accepted if empty. The type is detected from the content,
+	// the Content-Type sent by the client is ignored.
+	AllowedTypes []string
+}
+
+// Upload is a file saved by SaveUpload.
+type Upload struct {
+	Key         string `json:"key"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// SaveUpload streams the file of the multipart form field to the container
+// Storage, the file is never held in memory as a whole. It returns a 400
+// HTTPError if the field is missing, a 413 HTTPError if the file exceeds
+// MaxSize and a 415 HTTPError if its type is not allowed.
+func (c *Context) SaveUpload(field string, opts UploadOptions) (*Upload, error) {
+	if c.Storage == nil {
+		return nil, errNoStorage
+	}
+
+	r, filename, err := c.uploadFile(field)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, badRequest(fmt.Sprintf("reading %s: %v", field, err))
+	}
+	head = head[:n]
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedType(contentType, opts.AllowedTypes) {
+		return nil, &HTTPError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_file_type", Message: fmt.Sprintf("file type %s is not allowed", contentType)}
+	}
+
+	key := opts.Key
+	if key == "" {
+		key = opts.Prefix + randomHex(16) + uploadExt(filename)
+	}
+
+	body := &uploadReader{r: io.MultiReader(bytes.NewReader(head), r), max: opts.MaxSize}
+	if err := c.Storage.Put(c, key, body, storage.PutOptions{ContentType: contentType}); err != nil {
+		if errors.Is(err, errUploadTooLarge) {
+			return nil, &HTTPError{Status: http.StatusRequestEntityTooLarge, Code: "upload_too_large", Message: fmt.Sprintf("file exceeds %d bytes", opts.MaxSize)}
+		}
+		if errors.Is(err, storage.ErrInvalidKey) {
+			return nil, badRequest(fmt.Sprintf("invalid key %q", key))
+		}
+		return nil, err
+	}
+
+	return &Upload{Key: key, Filename: filename, Size: body.n, ContentType: contentType}, nil
+}
+
+// uploadFile returns the file of the field, read from the body unless the
+// form has already been parsed.
+func (c *Context) uploadFile(field string) (io.ReadCloser, string, error) {
+	if c.Request.MultipartForm != nil {
+		fh, err := c.FormFile(field)
+		if err != nil {
+			return nil, "", badRequest(fmt.Sprintf("missing file %s", field))
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		return f, filepath.Base(fh.Filename), nil
+	}
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", badRequest(fmt.Sprintf("expected a multipart form: %v", err))
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", badRequest(fmt.Sprintf("missing file %s", field))
+		}
+		if err != nil {
+			return nil, "", badRequest(fmt.Sprintf("invalid multipart form: %v", err))
+		}
+
+		if part.FormName() == field && part.FileName() != "" {
+			return part, filepath.Base(part.FileName()), nil
+		}
+		part.Close()
+	}
+}
+
+// uploadReader counts the bytes read and fails once more than max are read.
+type uploadReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	if u.max > 0 && u.n > u.max {
+		return n, errUploadTooLarge
+	}
+	return n, err
+}
+
+func allowedType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, t := range allowed {
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadExt returns the extension of the file name if it is safe to use in a key.
+func uploadExt(filename string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if len(ext) < 2 || len(ext) > 10 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// ServeStorage serves the objects of the container Storage at the signed URLs
+// of its SignedURL, STORAGE_URL must point to prefix. The S3 storage signs
+// URLs of the bucket and does not need it. Only images and PDFs are displayed
+// by the browser, the other objects are downloaded as attachments so that an
+// uploaded page cannot run scripts on the origin of the App.
+func (a *App) ServeStorage(prefix string) {
+	a.httpServer.registered = true
+
+	prefix = "/" + strings.Trim(prefix, "/")
+
+	a.Logger().Infof("Serving storage: %s", prefix)
+
+	a.httpServer.router.GET(prefix+"/*key", a.ginHandler(serveStorage))
+}
+
+// inlineTypes are the content types ServeStorage lets the browser display,
+// SVG images are left out as they can embed scripts.
+var inlineTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/avif":      true,
+	"application/pdf": true,
+}
+
+func serveStorage(c *Context) error {
+	verifier, ok := c.Storage.(interface {
+		VerifyURL(key string, query url.Values) error
+	})
+	if !ok {
+		return NewHTTPError(http.StatusNotFound, "", "")
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := verifier.VerifyURL(key, c.Request.URL.Query()); err != nil {
+		return NewHTTPError(http.StatusForbidden, "invalid_signature", "the URL is invalid or has expired")
+	}
+
+	info, err := c.Storage.Stat(c, key)
+	if errors.Is(err, storage.ErrNotExist) || errors.Is(err, storage.ErrInvalidKey) {
+		return NewHTTPError(http.StatusNotFound, "", "")
+	}
+	if err != nil {
+		return err
+	}
+
+	r, err := c.Storage.Get(c, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	c.Header("Cache-Control", "private")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if mediaType, _, _ := mime.ParseMediaType(info.ContentType); !inlineTypes[mediaType] {
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	}
+	if !info.ModTime.IsZero() {
+		c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, r, nil)
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package webber_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// multipartBody streams a form with a text field and a file.
+func multipartBody(field, filename string, data io.Reader) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		_ = mw.WriteField("title", "upload")
+		w, err := mw.CreateFormFile(field, filename)
+		if err == nil {
+			_, err = io.Copy(w, data)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, mw.FormDataContentType()
+}
+
+func TestSaveUpload(t *testing.T) {
+	for _, driver := range []string{"memory", "local"} {
+		t.Run(driver, func(t *testing.T) {
+			app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+				"STORAGE_DRIVER": driver,
+				"STORAGE_PATH":   t.TempDir(),
+				"STORAGE_URL":    "/files",
+				"STORAGE_SECRET": "secret",
+			}))
+
+			app.PostE("/upload", func(c *webber.Context) error {
+				u, err := c.SaveUpload("file", webber.UploadOptions{Prefix: "up/", MaxSize: 1 << 20, AllowedTypes: []string{"image/*"}})
+				if err != nil {
+					return err
+				}
+				c.JSON(http.StatusCreated, u)
+				return nil
+			})
+			app.ServeStorage("/files")
+
+			client := app.Client()
+			upload := func(field, filename string, data io.Reader) *webbertest.Response {
+				body, contentType := multipartBody(field, filename, data)
+				return client.Post("/upload").WithBody(contentType, body).Do()
+			}
+
+			png := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 1000)...)
+
+			var u webber.Upload
+			upload("file", "../Photo.PNG", bytes.NewReader(png)).Status(http.StatusCreated).JSON(&u)
+
+			if !strings.HasPrefix(u.Key, "up/") || !strings.HasSuffix(u.Key, ".png") {
+				t.Errorf("unexpected key %q", u.Key)
+			}
+			if u.Filename != "Photo.PNG" || u.Size != int64(len(png)) || u.ContentType != "image/png" {
+				t.Errorf("unexpected upload %+v", u)
+			}
+
+			upload("file", "a.png", strings.NewReader("plain text")).Status(http.StatusUnsupportedMediaType)
+			upload("other", "a.png", bytes.NewReader(png)).Status(http.StatusBadRequest)
+			upload("file", "big.png", io.MultiReader(bytes.NewReader(pngHeader), io.LimitReader(zeroReader{}, 2<<20))).
+				Status(http.StatusRequestEntityTooLarge)
+			client.Post("/upload").WithJSON(map[string]string{}).Do().Status(http.StatusBadRequest)
+
+			objects, err := app.Container().Storage.List(context.Background(), "up/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != 1 {
+				t.Errorf("expected only the accepted upload to be stored, got %+v", objects)
+			}
+
+			signed, err := app.Container().Storage.SignedURL(context.Background(), u.Key, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client.Get(signed).Do().
+				Status(http.StatusOK).
+				Header("Content-Type", "image/png").
+				Header("Cache-Control", "private").
+				Header("X-Content-Type-Options", "nosniff").
+				Header("Content-Disposition", "")
+			client.Get(signed + "0").Do().Status(http.StatusForbidden)
+			client.Get(strings.Replace(signed, u.Key, "up/other.png", 1)).Do().Status(http.StatusForbidden)
+		})
+	}
+}
+
+func TestServeStorageAttachment(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"STORAGE_DRIVER": "memory",
+		"STORAGE_URL":    "/files",
+		"STORAGE_SECRET": "secret",
+	}))
+
+	app.PostE("/upload", func(c *webber.Context) error {
+		u, err := c.SaveUpload("file", webber.UploadOptions{})
+		if err != nil {
+			return err
+		}
+		c.JSON(http.StatusCreated, u)
+		return nil
+	})
+	app.ServeStorage("/files")
+
+	client := app.Client()
+
+	var u webber.Upload
+	body, contentType := multipartBody("file", "page.html", strings.NewReader("<html><script>alert(1)</script></html>"))
+	client.Post("/upload").WithBody(contentType, body).Do().Status(http.StatusCreated).JSON(&u)
+
+	signed, err := app.Container().Storage.SignedURL(context.Background(), u.Key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The page is downloaded instead of running on the origin of the App
+	client.Get(signed).Do().
+		Status(http.StatusOK).
+		Header("X-Content-Type-Options", "nosniff").
+		Header("Content-Disposition", "attachment; filename="+path.Base(u.Key))
+}
+
+func TestSaveUploadWithoutStorage(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"STORAGE_DRIVER": "",
+	}))
+
+	var err error
+	app.PostE("/upload", func(c *webber.Context) error {
+		_, err = c.SaveUpload("file", webber.UploadOptions{})
+		return nil
+	})
+
+	body, contentType := multipartBody("file", "a.txt", strings.NewReader("a"))
+	app.Client().Post("/upload").WithBody(contentType, body).Do()
+
+	if err == nil {
+		t.Errorf("expected an error without storage")
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
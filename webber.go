@@ -3,21 +3,32 @@ package webber
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
+	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/xbmlz/webber/config"
 	"github.com/xbmlz/webber/container"
 	"github.com/xbmlz/webber/log"
 )
 
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	defaultUpgradeTimeout  = 30 * time.Second
+	defaultSocketMode      = 0o660
+)
+
+var errDBNotConfigured = errors.New("database is not configured")
+
 // App is the main struct for the webber package
 type App struct {
 	// Config can be used to get configuration values from the environment
@@ -28,129 +39,356 @@ type App struct {
 	cronRegistered bool
 	cron           *crontab
 
-	httpServer     *httpServer
-	httpRegistered bool
+	httpServer  *httpServer
+	adminServer *httpServer
+
+	errorHandler ErrorHandler
+	codecs       codecs
+	doc          Doc
+	hub          *Hub
+	sse          sseStreams
+	grpc         *grpcServer
+
+	lifecycle lifecycle
+	modules   map[string]Module
+	workers   workers
+	health    health
+	commands  []Command
+
+	// models and seeds registered with AddModels and AddSeeds
+	models []interface{}
+	seeds  []interface{}
+
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+	shutdownOnce    sync.Once
+	shutdownErr     error
+
+	upgradeTimeout time.Duration
+	upgrading      atomic.Bool
 }
 
-func New() *App {
-	app := &App{}
-	app.loadConfig()
-	app.container = container.New(app.Config)
+// New creates an App. Without options the config is loaded from the env files
+// in ./configs or the working directory, and the logger, database and redis
+// are built from it.
+func New(opts ...Option) *App {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	app := &App{errorHandler: DefaultErrorHandler, codecs: defaultCodecs()}
+	app.loadConfig(o)
+	app.loadContainer(o)
 
 	// HTTP Server
 	host := app.Config.GetString("HTTP_HOST", "localhost")
 	port, _ := app.Config.GetInt("HTTP_PORT", 8080)
+	if o.httpAddr != "" {
+		if h, p, err := splitHostPort(o.httpAddr); err != nil {
+			app.Logger().Errorf("Invalid HTTP address %q: %v", o.httpAddr, err)
+		} else {
+			host, port = h, p
+		}
+	}
 	mode := app.Config.GetString("GIN_MODE", "release")
-	app.httpServer = newHTTPServer(app.container, host, port, mode)
+	app.httpServer = newHTTPServer(app.container, "HTTP", host, port, mode)
 	app.httpServer.certFile = app.Config.GetString("CERT_FILE", "")
 	app.httpServer.keyFile = app.Config.GetString("KEY_FILE", "")
+	app.httpServer.tls = tlsSettings{
+		clientCA:     app.Config.GetString("TLS_CLIENT_CA", ""),
+		clientAuth:   app.Config.GetString("TLS_CLIENT_AUTH", ""),
+		minVersion:   app.Config.GetString("TLS_MIN_VERSION", ""),
+		cipherSuites: app.Config.GetString("TLS_CIPHER_SUITES", ""),
+	}
+	app.httpServer.listen = app.Config.GetString("HTTP_LISTEN", "")
+	app.httpServer.listener = o.listener
+	if mode, err := strconv.ParseUint(app.Config.GetString("HTTP_SOCKET_MODE", "0660"), 8, 32); err != nil {
+		app.Logger().Errorf("Invalid HTTP_SOCKET_MODE: %v", err)
+		app.httpServer.socketMode = defaultSocketMode
+	} else {
+		app.httpServer.socketMode = os.FileMode(mode)
+	}
+
+	// pprof is only exposed on the public server when explicitly enabled
+	if enabled, _ := app.Config.GetBool("HTTP_PPROF", false); enabled {
+		pprof.Register(app.httpServer.router)
+	}
+
+	app.initAdminServer(mode)
+	app.initHealth()
+	app.initOpenAPI()
+	app.hub = newHub(app)
+	app.initSSE()
+
+	app.shutdownTimeout, _ = config.GetDuration(app.Config, "SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	app.shutdownDelay, _ = config.GetDuration(app.Config, "SHUTDOWN_DELAY", 0)
+	app.upgradeTimeout, _ = config.GetDuration(app.Config, "UPGRADE_TIMEOUT", defaultUpgradeTimeout)
 
 	return app
 }
 
-func (a *App) loadConfig() {
-	var configPath string
-	if _, err := os.Stat("./configs"); err == nil {
-		configPath = "./configs"
+func (a *App) loadConfig(o *options) {
+	switch {
+	case o.config != nil:
+		a.Config = o.config
+		return
+	case o.container != nil && o.container.Config != nil:
+		a.Config = o.container.Config
+		return
+	}
+
+	configPath := o.configPath
+	if configPath == "" {
+		if _, err := os.Stat("./configs"); err == nil {
+			configPath = "./configs"
+		}
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = log.New(log.LevelInfo)
+	}
+
+	a.Config = config.New(configPath, logger)
+}
+
+func (a *App) loadContainer(o *options) {
+	if o.container == nil {
+		var opts []container.Option
+		if o.logger != nil {
+			opts = append(opts, container.WithLogger(o.logger))
+		}
+		if o.withoutDB {
+			opts = append(opts, container.WithoutDB())
+		}
+		if o.withoutRedis {
+			opts = append(opts, container.WithoutRedis())
+		}
+
+		a.container = container.New(a.Config, opts...)
+		return
+	}
+
+	a.container = o.container
+	if a.container.Config == nil {
+		a.container.Config = a.Config
 	}
+	if a.container.Logger == nil {
+		a.container.Logger = log.NewWithConfg(a.Config)
+	}
+}
 
-	a.Config = config.New(configPath, log.New(log.LevelInfo))
+// Shutdown gracefully stops the App in phases: the readiness check starts failing,
+// the event streams are closed, the HTTP server stops accepting connections
+// and drains in-flight requests, the gRPC server drains its calls, the websocket
+// connections are closed, the running cron jobs and the workers are awaited,
+// the stop hooks run in reverse order, the admin server is stopped and finally
+// Redis and the database are closed.
+// Shutdown is safe to call more than once, subsequent calls return the first result.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		a.shutdownErr = a.shutdown(ctx)
+	})
+	return a.shutdownErr
 }
 
-func (a *App) Run() {
-	// Create a context that is canceled on receiving termination signals
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+func (a *App) shutdown(ctx context.Context) error {
+	var err error
 
-	// Goroutine to handle shutdown when context is canceled
-	go func() {
-		<-ctx.Done()
+	// Readiness fails before the drain starts, SHUTDOWN_DELAY gives
+	// load balancers time to notice before connections are refused
+	err = errors.Join(err, a.shutdownPhase("marking app as not ready", func() error {
+		a.health.ready.Store(false)
+		if a.grpc != nil {
+			a.grpc.health.Shutdown()
+		}
 
-		// Create a shutdown context with a timeout
-		shutdownCtx, done := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
-		defer done()
+		select {
+		case <-time.After(a.shutdownDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
+
+	// Event streams never end on their own, they would keep the HTTP server from shutting down
+	err = errors.Join(err, a.shutdownPhase("closing event streams", func() error {
+		a.sse.close()
+		return nil
+	}))
+
+	if a.httpServer != nil && a.httpServer.srv != nil {
+		err = errors.Join(err, a.shutdownPhase("stopping HTTP server and draining in-flight requests", func() error {
+			return a.httpServer.Shutdown(ctx)
+		}))
+	}
 
-		_ = a.Shutdown(shutdownCtx)
-	}()
+	if a.grpc != nil {
+		err = errors.Join(err, a.shutdownPhase("stopping gRPC server", func() error {
+			return a.grpc.Shutdown(ctx)
+		}))
+	}
 
-	wg := sync.WaitGroup{}
+	// Hijacked connections are not drained by the HTTP server
+	err = errors.Join(err, a.shutdownPhase("closing websocket connections", func() error {
+		return a.hub.shutdown(ctx)
+	}))
 
-	if a.httpRegistered {
-		wg.Add(1)
+	if a.cron != nil {
+		err = errors.Join(err, a.shutdownPhase("waiting for running cron jobs", func() error {
+			return a.cron.Shutdown(ctx)
+		}))
+	}
 
-		go func(s *httpServer) {
-			defer wg.Done()
-			s.Run(a.container)
-		}(a.httpServer)
+	if len(a.workers.list) > 0 {
+		err = errors.Join(err, a.shutdownPhase("stopping workers", func() error {
+			return a.stopWorkers(ctx)
+		}))
 	}
 
-	if a.cronRegistered {
-		wg.Add(1)
+	if len(a.lifecycle.stop) > 0 {
+		err = errors.Join(err, a.shutdownPhase("running stop hooks", func() error {
+			return a.runHooksReverse(ctx, a.lifecycle.stop)
+		}))
+	}
 
-		go func(c *crontab) {
-			defer wg.Done()
-			c.Start()
-			<-ctx.Done()
-			c.Stop()
-		}(a.cron)
+	if a.adminServer != nil && a.adminServer.srv != nil {
+		err = errors.Join(err, a.shutdownPhase("stopping admin server", func() error {
+			return a.adminServer.Shutdown(ctx)
+		}))
 	}
 
-	wg.Wait()
-}
+	if a.container.Redis != nil {
+		err = errors.Join(err, a.shutdownPhase("closing redis connection", a.container.Redis.Close))
+	}
 
-func (a *App) Shutdown(ctx context.Context) error {
-	var err error
-	if a.httpServer != nil {
-		err = errors.Join(err, a.httpServer.Shutdown(ctx))
+	if a.container.DB != nil {
+		err = errors.Join(err, a.shutdownPhase("closing database connection pool", a.container.DB.Close))
 	}
+
 	return err
 }
 
-func (a *App) addRoute(method, path string, handler HandlerFunc) {
-	a.httpRegistered = true
+func (a *App) shutdownPhase(name string, fn func() error) error {
+	start := time.Now()
 
-	a.httpServer.router.Handle(method, path, func(ctx *gin.Context) {
-		handler(&Context{
-			Container: a.container,
-			Context:   ctx,
-		})
-	})
+	a.Logger().Infof("Shutdown: %s", name)
+
+	if err := fn(); err != nil {
+		a.Logger().Errorf("Shutdown: %s failed after %s: %v", name, time.Since(start), err)
+		return err
+	}
+
+	a.Logger().Infof("Shutdown: %s done in %s", name, time.Since(start))
+
+	return nil
+}
+
+func (a *App) addRoute(method, path string, handler Handler) {
+	a.handle(method, path, routeHandler{fn: handler})
+}
+
+func (a *App) handle(method, path string, h routeHandler) {
+	a.httpServer.registered = true
+
+	a.httpServer.router.Handle(method, path, a.ginHandler(h.fn))
+	a.doc.addRoute(method, path, h)
 }
 
 func (a *App) Get(path string, handler HandlerFunc) {
+	a.addRoute(http.MethodGet, path, handler.handler())
+}
+
+// GetE registers a handler returning an error, see Handler.
+func (a *App) GetE(path string, handler Handler) {
 	a.addRoute(http.MethodGet, path, handler)
 }
 
 func (a *App) Post(path string, handler HandlerFunc) {
+	a.addRoute(http.MethodPost, path, handler.handler())
+}
+
+// PostE registers a handler returning an error, see Handler.
+func (a *App) PostE(path string, handler Handler) {
 	a.addRoute(http.MethodPost, path, handler)
 }
 
 func (a *App) Put(path string, handler HandlerFunc) {
+	a.addRoute(http.MethodPut, path, handler.handler())
+}
+
+// PutE registers a handler returning an error, see Handler.
+func (a *App) PutE(path string, handler Handler) {
 	a.addRoute(http.MethodPut, path, handler)
 }
 
 func (a *App) Patch(path string, handler HandlerFunc) {
+	a.addRoute(http.MethodPatch, path, handler.handler())
+}
+
+// PatchE registers a handler returning an error, see Handler.
+func (a *App) PatchE(path string, handler Handler) {
 	a.addRoute(http.MethodPatch, path, handler)
 }
 
 func (a *App) Delete(path string, handler HandlerFunc) {
+	a.addRoute(http.MethodDelete, path, handler.handler())
+}
+
+// DeleteE registers a handler returning an error, see Handler.
+func (a *App) DeleteE(path string, handler Handler) {
 	a.addRoute(http.MethodDelete, path, handler)
 }
 
+// Any registers the handler for all the HTTP methods.
+func (a *App) Any(path string, handler HandlerFunc) {
+	a.AnyE(path, handler.handler())
+}
+
+// AnyE registers a handler returning an error for all the HTTP methods.
+func (a *App) AnyE(path string, handler Handler) {
+	for _, method := range anyMethods {
+		a.addRoute(method, path, handler)
+	}
+}
+
 func (a *App) Logger() log.Logger {
 	return a.container.Logger
 }
 
+// Container returns the container shared by the handlers, cron jobs and workers.
+func (a *App) Container() *container.Container {
+	return a.container
+}
+
+// Handler returns the http.Handler of the public HTTP server.
+func (a *App) Handler() http.Handler {
+	a.serveHealth()
+	return a.httpServer.router
+}
+
+// Use adds gin middleware to the public HTTP server, it only applies to the
+// routes added afterwards.
 func (a *App) Use(middleware ...gin.HandlerFunc) {
 	a.httpServer.router.Use(middleware...)
 }
 
-func (a *App) Group(prefix string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
-	return a.httpServer.router.Group(prefix, handlers...)
+// UseMiddleware adds middleware receiving the *Context to the public HTTP
+// server, it only applies to the routes added afterwards.
+func (a *App) UseMiddleware(middleware ...Middleware) {
+	a.httpServer.router.Use(a.ginMiddleware(middleware)...)
+}
+
+// Group returns a group of routes of the public HTTP server sharing the
+// path prefix and gin middleware, see RouterGroup.Use for middleware
+// receiving the *Context.
+func (a *App) Group(prefix string, middleware ...gin.HandlerFunc) *RouterGroup {
+	return &RouterGroup{app: a, server: a.httpServer, group: a.httpServer.router.Group(prefix, middleware...)}
 }
 
 func (a *App) AddStaticFiles(url, root string) {
-	a.httpRegistered = true
+	a.httpServer.registered = true
 	if !strings.HasPrefix(root, "./") && !filepath.IsAbs(root) {
 		root = "./" + root
 	}
@@ -173,10 +411,16 @@ func (a *App) AddStaticFiles(url, root string) {
 }
 
 func (a *App) MigrateDB(values ...interface{}) error {
+	if a.container.DB == nil {
+		return errDBNotConfigured
+	}
 	return a.container.DB.AutoMigrate(values...)
 }
 
 func (a *App) SeedDB(values ...interface{}) error {
+	if a.container.DB == nil {
+		return errDBNotConfigured
+	}
 	for _, value := range values {
 		if err := a.container.DB.FirstOrCreate(value).Error; err != nil {
 			return err
@@ -185,21 +429,124 @@ func (a *App) SeedDB(values ...interface{}) error {
 	return nil
 }
 
-func (a *App) AddCronJob(spec string, jobFunc CronFunc) {
+// AddModels registers models to migrate later, unlike MigrateDB. They are migrated
+// when the App starts, unless DB_AUTO_MIGRATE is false, and by the migrate command of Execute.
+func (a *App) AddModels(values ...interface{}) {
+	a.models = append(a.models, values...)
+}
+
+// AddSeeds registers records to create later if they don't exist, unlike SeedDB.
+// They are seeded when the App starts, unless DB_AUTO_MIGRATE is false, and by
+// the seed command of Execute.
+func (a *App) AddSeeds(values ...interface{}) {
+	a.seeds = append(a.seeds, values...)
+}
+
+// migrate migrates the models registered with AddModels.
+func (a *App) migrate() error {
+	if len(a.models) == 0 {
+		return nil
+	}
+	a.Logger().Infof("Migrating %d models", len(a.models))
+	return a.MigrateDB(a.models...)
+}
+
+// seed creates the records registered with AddSeeds.
+func (a *App) seed() error {
+	if len(a.seeds) == 0 {
+		return nil
+	}
+	a.Logger().Infof("Seeding %d records", len(a.seeds))
+	return a.SeedDB(a.seeds...)
+}
+
+func (a *App) AddCronJob(spec string, jobFunc CronFunc, opts ...CronOption) {
 	if a.cron == nil {
 		a.cron = NewCron(a.container)
 	}
 
 	a.cronRegistered = true
 
-	_, err := a.cron.AddFunc(spec, func() {
-		jobFunc(&Context{
-			Context:   nil,
-			Container: a.container,
-		})
-	})
+	job := &cronJob{
+		name: spec,
+		spec: spec,
+		fn: func() {
+			jobFunc(&Context{
+				Context:   nil,
+				Container: a.container,
+			})
+		},
+	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
 
+	if job.specKey != "" {
+		job.spec = a.Config.GetString(job.specKey, spec)
+	}
+
+	id, err := a.cron.AddFunc(job.spec, job.fn)
 	if err != nil {
 		a.Logger().Errorf("Failed to add cron job: %s", err.Error())
+		return
+	}
+
+	job.id = id
+	a.cron.jobs = append(a.cron.jobs, job)
+
+	if job.specKey != "" {
+		config.OnChange(a.Config, []string{job.specKey}, func(_, _ map[string]string) {
+			newSpec := a.Config.GetString(job.specKey, spec)
+			if err := a.cron.reschedule(job, newSpec); err != nil {
+				a.Logger().Errorf("Failed to reschedule cron job %s: %v", job.name, err)
+				return
+			}
+			a.Logger().Infof("Rescheduled cron job %s to %s", job.name, newSpec)
+		})
+	}
+}
+
+func splitHostPort(addr string) (string, int, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return host, port, nil
+}
+
+// positiveDuration reads a duration that must be positive, an invalid value
+// is logged and def is used instead.
+func (a *App) positiveDuration(key string, def time.Duration) time.Duration {
+	d, err := config.GetDuration(a.Config, key, def)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("%s is not positive", d)
+	}
+	if err != nil {
+		a.Logger().Errorf("Invalid %s, using %s: %v", key, def, err)
+		return def
 	}
+
+	return d
+}
+
+// positiveInt reads an int that must be positive, an invalid value is logged
+// and def is used instead.
+func (a *App) positiveInt(key string, def int) int {
+	n, err := a.Config.GetInt(key, def)
+	if err == nil && n <= 0 {
+		err = fmt.Errorf("%d is not positive", n)
+	}
+	if err != nil {
+		a.Logger().Errorf("Invalid %s, using %d: %v", key, def, err)
+		return def
+	}
+
+	return n
 }
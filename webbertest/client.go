@@ -0,0 +1,218 @@
+package webbertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Client sends requests to an http.Handler in-process and records the responses.
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+	headers http.Header
+}
+
+// NewClient returns a Client for any http.Handler.
+func NewClient(t testing.TB, handler http.Handler) *Client {
+	return &Client{t: t, handler: handler}
+}
+
+// WithHeader sets a header sent with every request of the client.
+func (c *Client) WithHeader(key, value string) *Client {
+	if c.headers == nil {
+		c.headers = http.Header{}
+	}
+	c.headers.Set(key, value)
+	return c
+}
+
+func (c *Client) Get(path string) *Request {
+	return c.Request(http.MethodGet, path)
+}
+
+func (c *Client) Post(path string) *Request {
+	return c.Request(http.MethodPost, path)
+}
+
+func (c *Client) Put(path string) *Request {
+	return c.Request(http.MethodPut, path)
+}
+
+func (c *Client) Patch(path string) *Request {
+	return c.Request(http.MethodPatch, path)
+}
+
+func (c *Client) Delete(path string) *Request {
+	return c.Request(http.MethodDelete, path)
+}
+
+// Request starts building a request with the given method and path.
+func (c *Client) Request(method, path string) *Request {
+	r := &Request{
+		client:  c,
+		method:  method,
+		path:    path,
+		headers: http.Header{},
+		query:   url.Values{},
+	}
+	for k, v := range c.headers {
+		r.headers[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// Request is a request being built by a Client.
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	headers http.Header
+	query   url.Values
+	body    io.Reader
+}
+
+// WithHeader sets a request header.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.headers.Set(key, value)
+	return r
+}
+
+// WithQuery adds a query parameter.
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithBody sets the request body.
+func (r *Request) WithBody(contentType string, body io.Reader) *Request {
+	r.headers.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// WithJSON sets the request body to the JSON encoding of v.
+func (r *Request) WithJSON(v interface{}) *Request {
+	r.client.t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.client.t.Fatalf("webbertest: encode request body: %v", err)
+	}
+	return r.WithBody("application/json", bytes.NewReader(b))
+}
+
+// WithForm sets the request body to the URL encoded form values.
+func (r *Request) WithForm(values url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// Do sends the request and returns the recorded response.
+func (r *Request) Do() *Response {
+	r.client.t.Helper()
+
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	for k, v := range r.headers {
+		req.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+
+	return &Response{t: r.client.t, ResponseRecorder: w}
+}
+
+// Response is a recorded response with fluent assertions.
+// Failed assertions are reported with t.Errorf, so all of them are checked.
+type Response struct {
+	*httptest.ResponseRecorder
+
+	t testing.TB
+}
+
+// Status asserts the response status code.
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+
+	if r.Code != code {
+		r.t.Errorf("webbertest: expected status %d, got %d: %s", code, r.Code, r.Body.String())
+	}
+	return r
+}
+
+// Header asserts the value of a response header.
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+
+	if got := r.Result().Header.Get(key); got != value {
+		r.t.Errorf("webbertest: expected header %s %q, got %q", key, value, got)
+	}
+	return r
+}
+
+// BodyContains asserts that the response body contains s.
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+
+	if !strings.Contains(r.Body.String(), s) {
+		r.t.Errorf("webbertest: expected body to contain %q, got %q", s, r.Body.String())
+	}
+	return r
+}
+
+// JSON decodes the response body into v.
+func (r *Response) JSON(v interface{}) *Response {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Errorf("webbertest: decode response body: %v: %s", err, r.Body.String())
+	}
+	return r
+}
+
+// JSONEq asserts that the response body is JSON equal to the encoding of expected,
+// expected can be a value or a JSON string.
+func (r *Response) JSONEq(expected interface{}) *Response {
+	r.t.Helper()
+
+	var want, got interface{}
+
+	b, ok := expected.(string)
+	if !ok {
+		encoded, err := json.Marshal(expected)
+		if err != nil {
+			r.t.Errorf("webbertest: encode expected value: %v", err)
+			return r
+		}
+		b = string(encoded)
+	}
+
+	if err := json.Unmarshal([]byte(b), &want); err != nil {
+		r.t.Errorf("webbertest: decode expected value: %v", err)
+		return r
+	}
+	if err := json.Unmarshal(r.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("webbertest: decode response body: %v: %s", err, r.Body.String())
+		return r
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("webbertest: expected JSON %s, got %s", b, r.Body.String())
+	}
+	return r
+}
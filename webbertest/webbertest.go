@@ -0,0 +1,177 @@
+// Package webbertest builds webber Apps for tests: an in-memory SQLite database,
+// an in-process Redis stand-in, an in-memory storage and a captured logger,
+// without touching the process environment or the filesystem.
+package webbertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/config"
+	"github.com/xbmlz/webber/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// App is a webber.App wired for tests.
+type App struct {
+	*webber.App
+
+	t     testing.TB
+	redis *miniredis.Miniredis
+	logs  *observer.ObservedLogs
+}
+
+// Option configures a test App.
+type Option func(*options)
+
+type options struct {
+	values       map[string]string
+	withoutDB    bool
+	withoutRedis bool
+}
+
+// WithConfig sets config values, they override the test defaults.
+func WithConfig(values map[string]string) Option {
+	return func(o *options) {
+		for k, v := range values {
+			o.values[k] = v
+		}
+	}
+}
+
+// WithoutDB creates the App without a database.
+func WithoutDB() Option {
+	return func(o *options) {
+		o.withoutDB = true
+	}
+}
+
+// WithoutRedis creates the App without redis.
+func WithoutRedis() Option {
+	return func(o *options) {
+		o.withoutRedis = true
+	}
+}
+
+// New creates an App backed by an in-memory SQLite database, an in-process
+// Redis server and an in-memory storage. Everything is shut down when the test completes.
+func New(t testing.TB, opts ...Option) *App {
+	t.Helper()
+
+	o := &options{
+		values: map[string]string{
+			"GIN_MODE":               gin.TestMode,
+			"DB_DRIVER":              "sqlite",
+			"DB_NAME":                ":memory:",
+			"DB_LOG_LEVEL":           "silent",
+			"DB_MAX_OPEN_CONNECTION": "1",
+			"STORAGE_DRIVER":         "memory",
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	app := &App{t: t}
+
+	var webberOpts []webber.Option
+
+	if o.withoutDB {
+		webberOpts = append(webberOpts, webber.WithoutDB())
+	}
+
+	if o.withoutRedis {
+		webberOpts = append(webberOpts, webber.WithoutRedis())
+	} else {
+		app.redis = miniredis.RunT(t)
+		o.values["REDIS_HOST"] = app.redis.Host()
+		o.values["REDIS_PORT"] = app.redis.Port()
+	}
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	app.logs = logs
+
+	webberOpts = append(webberOpts,
+		webber.WithConfig(config.NewMap(o.values)),
+		webber.WithLogger(log.NewWithZap(zap.New(core))),
+	)
+
+	app.App = webber.New(webberOpts...)
+
+	t.Cleanup(func() {
+		if err := app.Shutdown(context.Background()); err != nil {
+			t.Errorf("webbertest: shutdown: %v", err)
+		}
+	})
+
+	return app
+}
+
+// MiniRedis returns the in-process Redis server, it is nil if the App was created WithoutRedis.
+func (a *App) MiniRedis() *miniredis.Miniredis {
+	return a.redis
+}
+
+// Logs returns the log entries written by the App.
+func (a *App) Logs() *observer.ObservedLogs {
+	return a.logs
+}
+
+// Fixtures migrates and creates the given values inside a transaction that is
+// rolled back when the test completes. Until then, handlers, cron jobs and
+// workers of the App see the fixtures through the container DB.
+func (a *App) Fixtures(t testing.TB, values ...interface{}) {
+	t.Helper()
+
+	database := a.Container().DB
+	if database == nil {
+		t.Fatalf("webbertest: fixtures: database is not configured")
+	}
+
+	orig := database.DB
+	tx := orig.Begin()
+	if tx.Error != nil {
+		t.Fatalf("webbertest: fixtures: %v", tx.Error)
+	}
+
+	database.DB = tx
+	t.Cleanup(func() {
+		tx.Rollback()
+		database.DB = orig
+	})
+
+	for _, value := range values {
+		if err := tx.AutoMigrate(value); err != nil {
+			t.Fatalf("webbertest: fixtures: migrate %T: %v", value, err)
+		}
+		if err := tx.Create(value).Error; err != nil {
+			t.Fatalf("webbertest: fixtures: create %T: %v", value, err)
+		}
+	}
+}
+
+// NewContext returns a Context bound to the given request for calling a single handler,
+// and the recorder capturing its response. Path parameters can be set on ctx.Params.
+func (a *App) NewContext(req *http.Request) (*webber.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	return &webber.Context{
+		Container: a.Container(),
+		Context:   c,
+	}, w
+}
+
+// Client returns a client sending requests to the App handler in-process.
+func (a *App) Client() *Client {
+	return &Client{t: a.t, handler: a.Handler()}
+}
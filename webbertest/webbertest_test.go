@@ -0,0 +1,145 @@
+package webbertest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+type user struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestClient(t *testing.T) {
+	app := webbertest.New(t)
+
+	app.PostE("/users", func(c *webber.Context) error {
+		var u user
+		if err := c.ShouldBindJSON(&u); err != nil {
+			return err
+		}
+		c.Header("X-Test", c.Query("q"))
+		c.JSON(http.StatusCreated, u)
+		return nil
+	})
+
+	var got user
+	app.Client().
+		WithHeader("X-Client", "1").
+		Post("/users").
+		WithQuery("q", "a b").
+		WithJSON(user{ID: 1, Name: "ann"}).
+		Do().
+		Status(http.StatusCreated).
+		Header("X-Test", "a b").
+		BodyContains(`"ann"`).
+		JSONEq(`{"id": 1, "name": "ann"}`).
+		JSONEq(user{ID: 1, Name: "ann"}).
+		JSON(&got)
+
+	if got.Name != "ann" {
+		t.Errorf("expected the decoded name ann, got %q", got.Name)
+	}
+
+	app.Client().Get("/missing").Do().Status(http.StatusNotFound)
+}
+
+func TestResponseAssertionsFail(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1}`))
+	})
+
+	rt := &recordingTB{TB: t}
+	webbertest.NewClient(rt, handler).Get("/").Do().
+		Status(http.StatusTeapot).
+		Header("Content-Type", "text/plain").
+		BodyContains("name").
+		JSONEq(`{"id": 2}`)
+
+	if rt.errors != 4 {
+		t.Errorf("expected 4 failed assertions, got %d", rt.errors)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	app := webbertest.New(t)
+
+	app.GetE("/users/:name", func(c *webber.Context) error {
+		var u user
+		if err := c.DB.Where("name = ?", c.Param("name")).First(&u).Error; err != nil {
+			return webber.NewHTTPError(http.StatusNotFound, "", "")
+		}
+		c.JSON(http.StatusOK, u)
+		return nil
+	})
+
+	t.Run("loaded", func(t *testing.T) {
+		app.Fixtures(t, &user{Name: "ann"}, &user{Name: "bob"})
+
+		app.Client().Get("/users/bob").Do().Status(http.StatusOK).JSONEq(user{ID: 2, Name: "bob"})
+	})
+
+	if app.Container().DB.Migrator().HasTable(&user{}) {
+		t.Errorf("expected the fixtures to be rolled back")
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	app := webbertest.New(t)
+
+	handler := func(c *webber.Context) error {
+		if err := c.Redis.Set(c, "user", c.Param("id"), 0).Err(); err != nil {
+			return err
+		}
+		c.Logger.Infof("stored user %s", c.Param("id"))
+		c.Status(http.StatusNoContent)
+		return nil
+	}
+
+	c, w := app.NewContext(httptest.NewRequest(http.MethodPut, "/users/7", nil))
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	c.Writer.WriteHeaderNow()
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if v, _ := app.MiniRedis().Get("user"); v != "7" {
+		t.Errorf("expected the user 7 in redis, got %q", v)
+	}
+	if n := app.Logs().FilterMessage("stored user 7").Len(); n != 1 {
+		t.Errorf("expected 1 log entry, got %d", n)
+	}
+}
+
+func TestWithout(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"STORAGE_DRIVER": "",
+	}))
+
+	if app.Container().DB != nil || app.Container().Redis != nil || app.Container().Storage != nil {
+		t.Errorf("expected no database, redis and storage")
+	}
+	if app.MiniRedis() != nil {
+		t.Errorf("expected no in-process redis")
+	}
+}
+
+// recordingTB counts the errors instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors int
+}
+
+func (r *recordingTB) Errorf(string, ...interface{}) {
+	r.errors++
+}
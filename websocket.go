@@ -0,0 +1,523 @@
+package webber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWebSocketPingInterval   = 30 * time.Second
+	defaultWebSocketBuffer         = 64
+	defaultWebSocketMaxMessageSize = 1 << 20
+	defaultWebSocketChannel        = "webber:websocket"
+
+	webSocketWriteTimeout = 10 * time.Second
+)
+
+// Message types of WebSocketConn.ReadMessage and WebSocketConn.WriteMessage.
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+var errWebSocketClosed = errors.New("websocket connection closed")
+
+// WebSocketHandler handles a WebSocket connection, the connection is closed
+// once it returns. A returned error is logged and the connection is closed
+// with an internal error status.
+type WebSocketHandler func(c *Context, conn *WebSocketConn) error
+
+// WebSocket registers a WebSocket endpoint on the public HTTP server.
+func (a *App) WebSocket(path string, handler WebSocketHandler) {
+	a.GetE(path, a.hub.handler(handler))
+}
+
+// WebSocket registers a WebSocket endpoint in the group.
+func (g *RouterGroup) WebSocket(path string, handler WebSocketHandler) {
+	g.GetE(path, g.app.hub.handler(handler))
+}
+
+// Hub returns the hub of the WebSocket connections of the App.
+func (a *App) Hub() *Hub {
+	return a.hub
+}
+
+// Hub tracks the WebSocket connections and their rooms. When Redis is
+// configured, broadcasts are published to WEBSOCKET_REDIS_CHANNEL so that
+// the connections of every instance receive them.
+type Hub struct {
+	app *App
+
+	// id identifies the instance in the messages published to Redis
+	id           string
+	channel      string
+	pingInterval time.Duration
+	buffer       int
+	// maxMessageSize is the size in bytes of the largest message read
+	maxMessageSize int64
+	upgrader       websocket.Upgrader
+
+	mu      sync.RWMutex
+	conns   map[*WebSocketConn]struct{}
+	rooms   map[string]map[*WebSocketConn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+
+	// used reports whether a WebSocket endpoint is registered, Redis is only
+	// subscribed to if it is
+	used   bool
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// hubMessage is a broadcast published to Redis.
+type hubMessage struct {
+	Origin string          `json:"origin"`
+	Room   string          `json:"room,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func newHub(a *App) *Hub {
+	h := &Hub{
+		app:     a,
+		id:      randomHex(8),
+		channel: a.Config.GetString("WEBSOCKET_REDIS_CHANNEL", defaultWebSocketChannel),
+		conns:   map[*WebSocketConn]struct{}{},
+		rooms:   map[string]map[*WebSocketConn]struct{}{},
+	}
+
+	h.pingInterval = a.positiveDuration("WEBSOCKET_PING_INTERVAL", defaultWebSocketPingInterval)
+	h.buffer = a.positiveInt("WEBSOCKET_BUFFER", defaultWebSocketBuffer)
+	h.maxMessageSize = int64(a.positiveInt("WEBSOCKET_MAX_MESSAGE_SIZE", defaultWebSocketMaxMessageSize))
+
+	// Without allowed origins the Origin header must match the Host header
+	if origins := a.Config.GetString("WEBSOCKET_ALLOWED_ORIGINS", ""); origins != "" {
+		h.upgrader.CheckOrigin = checkOrigin(strings.Split(origins, ","))
+	}
+
+	return h
+}
+
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	for i := range allowed {
+		allowed[i] = strings.TrimSpace(allowed[i])
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(a, origin) || strings.EqualFold(a, u.Host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (h *Hub) handler(handler WebSocketHandler) func(c *Context) error {
+	h.used = true
+
+	return func(c *Context) error {
+		h.mu.Lock()
+		if h.closing {
+			h.mu.Unlock()
+			return &HTTPError{Status: http.StatusServiceUnavailable, Message: "server is shutting down"}
+		}
+		h.wg.Add(1)
+		h.mu.Unlock()
+		defer h.wg.Done()
+
+		ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader already responded
+			return nil
+		}
+
+		conn := h.newConn(c, ws)
+
+		h.mu.Lock()
+		h.conns[conn] = struct{}{}
+		h.mu.Unlock()
+
+		defer h.remove(conn)
+
+		if err := handler(c, conn); err != nil && !errors.Is(err, errWebSocketClosed) && !isCloseError(err) {
+			c.Logger.Errorf("Error handling websocket %s: %v", c.Request.URL.Path, err)
+			conn.closeWith(websocket.CloseInternalServerErr, "internal error")
+		}
+
+		conn.closeWith(websocket.CloseNormalClosure, "")
+		<-conn.writerDone
+		ws.Close()
+
+		return nil
+	}
+}
+
+func isCloseError(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr)
+}
+
+func (h *Hub) remove(c *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.conns, c)
+	for room := range c.rooms {
+		h.leave(c, room)
+	}
+}
+
+func (h *Hub) leave(c *WebSocketConn, room string) {
+	delete(c.rooms, room)
+	if conns := h.rooms[room]; conns != nil {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Connections returns the number of local connections in the room,
+// all of them if the room is empty.
+func (h *Hub) Connections(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if room == "" {
+		return len(h.conns)
+	}
+	return len(h.rooms[room])
+}
+
+// Broadcast sends v as JSON to the connections that joined the room, all of
+// them if the room is empty. The connections of the other instances receive
+// it through Redis when it is configured. A connection whose buffer is full
+// is closed as a slow consumer.
+func (h *Hub) Broadcast(ctx context.Context, room string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := h.deliver(room, data); err != nil {
+		return err
+	}
+
+	if h.app.container.Redis == nil {
+		return nil
+	}
+
+	msg, err := json.Marshal(hubMessage{Origin: h.id, Room: room, Data: data})
+	if err != nil {
+		return err
+	}
+
+	return h.app.container.Redis.Publish(ctx, h.channel, msg).Err()
+}
+
+// deliver sends the data to the local connections of the room.
+func (h *Hub) deliver(room string, data []byte) error {
+	pm, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	conns := h.conns
+	if room != "" {
+		conns = h.rooms[room]
+	}
+	targets := make([]*WebSocketConn, 0, len(conns))
+	for c := range conns {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range targets {
+		select {
+		case c.send <- pm:
+		case <-c.ctx.Done():
+		default:
+			h.app.Logger().Warnf("Closing slow websocket connection %s", c.id)
+			c.closeWith(websocket.CloseTryAgainLater, "slow consumer")
+		}
+	}
+
+	return nil
+}
+
+// start subscribes to the broadcasts of the other instances.
+func (h *Hub) start(ctx context.Context) {
+	if !h.used || h.app.container.Redis == nil {
+		return
+	}
+
+	ctx, h.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	h.done = make(chan struct{})
+
+	sub := h.app.container.Redis.Subscribe(ctx, h.channel)
+
+	go func() {
+		defer close(h.done)
+		defer sub.Close()
+
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				var msg hubMessage
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					h.app.Logger().Errorf("Invalid websocket broadcast: %v", err)
+					continue
+				}
+				if msg.Origin == h.id {
+					continue
+				}
+
+				if err := h.deliver(msg.Room, msg.Data); err != nil {
+					h.app.Logger().Errorf("Error delivering websocket broadcast: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// shutdown sends a close frame to the connections and waits for their handlers
+// to return, the connections left when ctx is done are closed.
+func (h *Hub) shutdown(ctx context.Context) error {
+	if h.cancel != nil {
+		h.cancel()
+		<-h.done
+	}
+
+	h.mu.Lock()
+	h.closing = true
+	conns := make([]*WebSocketConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.closeWith(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	return ShutdownWithContext(ctx, func(context.Context) error {
+		h.wg.Wait()
+		return nil
+	}, func() error {
+		for _, c := range conns {
+			c.conn.Close()
+		}
+		return nil
+	})
+}
+
+// WebSocketConn is a WebSocket connection. Reads are done by the handler,
+// writes are queued and can be done from any goroutine.
+type WebSocketConn struct {
+	hub  *Hub
+	conn *websocket.Conn
+	id   string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	send       chan *websocket.PreparedMessage
+	quit       chan struct{}
+	writerDone chan struct{}
+	closeOnce  sync.Once
+	closeMsg   []byte
+
+	// rooms is guarded by the hub mutex
+	rooms map[string]struct{}
+}
+
+func (h *Hub) newConn(c *Context, ws *websocket.Conn) *WebSocketConn {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+
+	conn := &WebSocketConn{
+		hub:        h,
+		conn:       ws,
+		id:         c.RequestID(),
+		ctx:        ctx,
+		cancel:     cancel,
+		send:       make(chan *websocket.PreparedMessage, h.buffer),
+		quit:       make(chan struct{}),
+		writerDone: make(chan struct{}),
+		rooms:      map[string]struct{}{},
+	}
+
+	// A larger message closes the connection instead of being read into memory
+	ws.SetReadLimit(h.maxMessageSize)
+
+	// The client must answer the pings for the connection to stay open
+	ws.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	})
+
+	go conn.writeLoop()
+
+	return conn
+}
+
+func (c *WebSocketConn) writeLoop() {
+	defer close(c.writerDone)
+
+	ticker := time.NewTicker(c.hub.pingInterval)
+	defer ticker.Stop()
+
+	write := func(pm *websocket.PreparedMessage) bool {
+		c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+		if err := c.conn.WritePreparedMessage(pm); err != nil {
+			c.cancel()
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case pm := <-c.send:
+			if !write(pm) {
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout)); err != nil {
+				c.cancel()
+				return
+			}
+		case <-c.quit:
+			// The queued messages are sent before the close frame
+			for len(c.send) > 0 {
+				if !write(<-c.send) {
+					return
+				}
+			}
+			c.conn.WriteControl(websocket.CloseMessage, c.closeMsg, time.Now().Add(webSocketWriteTimeout))
+			return
+		}
+	}
+}
+
+// closeWith sends a close frame once the queued messages are written.
+func (c *WebSocketConn) closeWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeMsg = websocket.FormatCloseMessage(code, reason)
+		c.cancel()
+		close(c.quit)
+	})
+}
+
+// ID returns the ID of the connection, the ID of the upgrade request.
+func (c *WebSocketConn) ID() string {
+	return c.id
+}
+
+// Context returns the context of the connection, it is done once the
+// connection is closing.
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+// ReadMessage reads the next message and returns its type, TextMessage or BinaryMessage.
+// A message larger than WEBSOCKET_MAX_MESSAGE_SIZE bytes, 1 MiB by default, closes
+// the connection.
+func (c *WebSocketConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.cancel()
+	}
+	return messageType, data, err
+}
+
+// ReadJSON reads the next message into v.
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage queues a message, it blocks while the buffer of the connection is full.
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	pm, err := websocket.NewPreparedMessage(messageType, data)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case c.send <- pm:
+		return nil
+	case <-c.ctx.Done():
+		return errWebSocketClosed
+	}
+}
+
+// WriteJSON queues v as a JSON text message.
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// Join adds the connection to the room.
+func (c *WebSocketConn) Join(room string) {
+	h := c.hub
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.conns[c]; !ok {
+		return
+	}
+
+	c.rooms[room] = struct{}{}
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*WebSocketConn]struct{}{}
+	}
+	h.rooms[room][c] = struct{}{}
+}
+
+// Leave removes the connection from the room.
+func (c *WebSocketConn) Leave(room string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	c.hub.leave(c, room)
+}
+
+// Close closes the connection with a normal closure status.
+func (c *WebSocketConn) Close() error {
+	c.closeWith(websocket.CloseNormalClosure, "")
+	return nil
+}
@@ -0,0 +1,79 @@
+package webber_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+func TestWebSocketInvalidConfig(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"WEBSOCKET_PING_INTERVAL": "0s",
+		"WEBSOCKET_BUFFER":        "-1",
+	}))
+
+	app.WebSocket("/ws", func(c *webber.Context, conn *webber.WebSocketConn) error {
+		var m map[string]string
+		if err := conn.ReadJSON(&m); err != nil {
+			return err
+		}
+		return conn.WriteJSON(m)
+	})
+
+	for _, key := range []string{"WEBSOCKET_PING_INTERVAL", "WEBSOCKET_BUFFER"} {
+		if app.Logs().FilterMessageSnippet("Invalid "+key).Len() != 1 {
+			t.Errorf("expected the invalid %s to be logged", key)
+		}
+	}
+
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	if err := ws.WriteJSON(map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]string
+	if err := ws.ReadJSON(&m); err != nil || m["a"] != "b" {
+		t.Errorf("expected the message back, got %v, %v", m, err)
+	}
+}
+
+func TestWebSocketMaxMessageSize(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"WEBSOCKET_MAX_MESSAGE_SIZE": "16",
+	}))
+
+	read := make(chan error, 1)
+	app.WebSocket("/ws", func(c *webber.Context, conn *webber.WebSocketConn) error {
+		_, _, err := conn.ReadMessage()
+		read <- err
+		return nil
+	})
+
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 17))); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-read; !errors.Is(err, websocket.ErrReadLimit) {
+		t.Errorf("expected the read limit to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package webber
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkerMinBackoff = time.Second
+	defaultWorkerMaxBackoff = time.Minute
+)
+
+// WorkerFunc is a long-lived function, such as a queue consumer or a poller,
+// that runs until ctx is done.
+type WorkerFunc func(ctx *Context) error
+
+// WorkerState is the current state of a worker.
+type WorkerState string
+
+const (
+	WorkerIdle    WorkerState = "idle"
+	WorkerRunning WorkerState = "running"
+	WorkerBackoff WorkerState = "backoff"
+	WorkerStopped WorkerState = "stopped"
+	WorkerFailed  WorkerState = "failed"
+)
+
+// WorkerStatus reports the state of a worker for observability.
+type WorkerStatus struct {
+	Name      string      `json:"name"`
+	State     WorkerState `json:"state"`
+	Restarts  int         `json:"restarts"`
+	LastError string      `json:"last_error,omitempty"`
+	StartedAt time.Time   `json:"started_at"`
+}
+
+// WorkerOption configures a worker.
+type WorkerOption func(*worker)
+
+// WithWorkerBackoff sets the minimum and maximum delay between restarts,
+// the delay doubles after every consecutive failure.
+func WithWorkerBackoff(min, max time.Duration) WorkerOption {
+	return func(w *worker) {
+		w.minBackoff = min
+		w.maxBackoff = max
+	}
+}
+
+// WithWorkerMaxRestarts sets how many consecutive failures are restarted
+// before the worker is marked as failed. Zero means unlimited.
+func WithWorkerMaxRestarts(n int) WorkerOption {
+	return func(w *worker) {
+		w.maxRestarts = n
+	}
+}
+
+type worker struct {
+	name        string
+	fn          WorkerFunc
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxRestarts int
+
+	mu     sync.Mutex
+	status WorkerStatus
+}
+
+type workers struct {
+	list   []*worker
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// AddWorker registers a worker that is started by Run and stopped by Shutdown.
+// A worker that returns an error or panics is restarted with exponential backoff,
+// a worker that returns nil is not restarted.
+func (a *App) AddWorker(name string, fn WorkerFunc, opts ...WorkerOption) {
+	w := &worker{
+		name:       name,
+		fn:         fn,
+		minBackoff: defaultWorkerMinBackoff,
+		maxBackoff: defaultWorkerMaxBackoff,
+		status:     WorkerStatus{Name: name, State: WorkerIdle},
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	a.workers.list = append(a.workers.list, w)
+}
+
+// Workers returns the status of the registered workers.
+func (a *App) Workers() []WorkerStatus {
+	statuses := make([]WorkerStatus, 0, len(a.workers.list))
+	for _, w := range a.workers.list {
+		w.mu.Lock()
+		statuses = append(statuses, w.status)
+		w.mu.Unlock()
+	}
+	return statuses
+}
+
+// startWorkers runs the workers until stopWorkers is called.
+func (a *App) startWorkers(ctx context.Context) {
+	if len(a.workers.list) == 0 {
+		return
+	}
+
+	ctx, a.workers.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	for _, w := range a.workers.list {
+		a.workers.wg.Add(1)
+
+		go func(w *worker) {
+			defer a.workers.wg.Done()
+			a.superviseWorker(ctx, w)
+		}(w)
+	}
+}
+
+// stopWorkers cancels the workers and waits for them to return.
+func (a *App) stopWorkers(ctx context.Context) error {
+	if a.workers.cancel != nil {
+		a.workers.cancel()
+	}
+
+	return ShutdownWithContext(ctx, func(context.Context) error {
+		a.workers.wg.Wait()
+		return nil
+	}, nil)
+}
+
+func (a *App) superviseWorker(ctx context.Context, w *worker) {
+	backoff := w.minBackoff
+	failures := 0
+
+	for {
+		start := time.Now()
+		w.setRunning(start)
+
+		err := a.runWorker(ctx, w)
+
+		if ctx.Err() != nil {
+			w.setState(WorkerStopped, err)
+			return
+		}
+
+		if err == nil {
+			a.Logger().Infof("Worker %s finished", w.name)
+			w.setState(WorkerStopped, nil)
+			return
+		}
+
+		// A worker that ran for a while before failing starts over with the minimum backoff
+		if time.Since(start) > w.maxBackoff {
+			backoff = w.minBackoff
+			failures = 0
+		}
+
+		failures++
+		if w.maxRestarts > 0 && failures > w.maxRestarts {
+			a.Logger().Errorf("Worker %s failed %d times, giving up: %v", w.name, failures, err)
+			w.setState(WorkerFailed, err)
+			return
+		}
+
+		a.Logger().Errorf("Worker %s failed, restarting in %s: %v", w.name, backoff, err)
+		w.setState(WorkerBackoff, err)
+
+		select {
+		case <-ctx.Done():
+			w.setState(WorkerStopped, err)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, w.maxBackoff)
+	}
+}
+
+func (a *App) runWorker(ctx context.Context, w *worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.Logger().Errorf("Worker %s panicked: %v\n%s", w.name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return w.fn(&Context{
+		Container: a.container,
+		ctx:       ctx,
+	})
+}
+
+func (w *worker) setRunning(start time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status.State != WorkerIdle {
+		w.status.Restarts++
+	}
+	w.status.State = WorkerRunning
+	w.status.StartedAt = start
+}
+
+func (w *worker) setState(state WorkerState, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.State = state
+	if err != nil {
+		w.status.LastError = err.Error()
+	}
+}
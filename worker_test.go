@@ -0,0 +1,96 @@
+package webber_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xbmlz/webber"
+	"github.com/xbmlz/webber/webbertest"
+)
+
+// waitWorker waits until the worker is in the given state.
+func waitWorker(t *testing.T, app *webbertest.App, state webber.WorkerState) webber.WorkerStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := app.Workers()[0]
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the worker to be %s, got %+v", state, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWorkerRestartsAfterPanic(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"HTTP_HOST": "127.0.0.1",
+		"HTTP_PORT": "0",
+	}))
+
+	var runs atomic.Int32
+	restarted := make(chan struct{})
+	app.AddWorker("consumer", func(ctx *webber.Context) error {
+		if runs.Add(1) == 1 {
+			panic("boom")
+		}
+		close(restarted)
+		<-ctx.Done()
+		return ctx.Err()
+	}, webber.WithWorkerBackoff(time.Millisecond, 10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.RunContext(ctx) }()
+
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the worker to restart")
+	}
+	if status := app.Workers()[0]; status.State != webber.WorkerRunning || status.Restarts != 1 || !strings.Contains(status.LastError, "panic: boom") {
+		t.Errorf("expected one restart after the panic, got %+v", status)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if status := app.Workers()[0]; status.State != webber.WorkerStopped {
+		t.Errorf("expected the worker to be stopped, got %+v", status)
+	}
+}
+
+func TestWorkerMaxRestarts(t *testing.T) {
+	app := webbertest.New(t, webbertest.WithoutDB(), webbertest.WithoutRedis(), webbertest.WithConfig(map[string]string{
+		"HTTP_HOST": "127.0.0.1",
+		"HTTP_PORT": "0",
+	}))
+
+	var runs atomic.Int32
+	app.AddWorker("poller", func(ctx *webber.Context) error {
+		runs.Add(1)
+		return errors.New("unreachable")
+	}, webber.WithWorkerBackoff(time.Millisecond, 10*time.Millisecond), webber.WithWorkerMaxRestarts(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.RunContext(ctx) }()
+
+	status := waitWorker(t, app, webber.WorkerFailed)
+	if runs.Load() != 3 || status.Restarts != 2 || status.LastError != "unreachable" {
+		t.Errorf("expected the worker to give up after 2 restarts, got %d runs and %+v", runs.Load(), status)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}